@@ -5,23 +5,34 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
-func init() {
-	log.SetOutput(ioutil.Discard)
+// Enable uru's logger at the requested level, writing to w.
+func initLogging(opts options, w io.Writer) {
+	level := opts.logLevel
+	if opts.logFile && level == log.LevelOff {
+		level = log.LevelDebug
+	}
+	log.SetOutput(w)
+	log.SetLevel(level)
 }
 
-// Initialize uru's home directory, creating if necessary.
-func initHome(ctx *env.Context) {
-	uruHome := os.Getenv(`URU_HOME`)
+// Initialize uru's home directory, creating if necessary. A non-empty home
+// given by the `--home` option overrides URU_HOME.
+func initHome(ctx *env.Context, uruHome string) {
+	if uruHome == `` {
+		uruHome = os.Getenv(`URU_HOME`)
+	}
 	if uruHome == `` {
 		if runtime.GOOS == `windows` {
 			ctx.SetHome(filepath.Join(os.Getenv(`USERPROFILE`), `.uru`))
@@ -31,17 +42,16 @@ func initHome(ctx *env.Context) {
 	} else {
 		ctx.SetHome(uruHome)
 	}
-	log.Printf("[DEBUG] uru HOME is %s\n", ctx.Home())
 
 	if _, err := os.Stat(ctx.Home()); os.IsNotExist(err) {
-		log.Printf("[DEBUG] creating %s\n", ctx.Home())
+		log.Debug("creating uru home", "dir", ctx.Home())
 		os.Mkdir(ctx.Home(), os.ModeDir|0750)
 	}
 
 	// purge existing runners to prevent bogus environment changes
 	walk := func(path string, info os.FileInfo, err error) error {
 		if strings.HasPrefix(filepath.Base(path), `uru_lackee`) {
-			log.Printf("[DEBUG] deleting runner script %s\n", path)
+			log.Debug("deleting runner script", "file", path)
 			_ = os.Remove(path) // TODO throw away the error?
 		}
 		return nil
@@ -49,24 +59,38 @@ func initHome(ctx *env.Context) {
 	filepath.Walk(ctx.Home(), walk)
 }
 
+// Read uru's optional config file from uru's home directory.
+func initConfig(ctx *env.Context) error {
+	cfg, err := env.ReadConfigFile(filepath.Join(ctx.Home(), `config`))
+	if err != nil {
+		return fmt.Errorf("[ERROR] invalid config file\n  %v", err)
+	}
+	ctx.Config = cfg
+	log.Tracef("=== ctx.Config ===\n%#v", ctx.Config)
+
+	return nil
+}
+
 // Import all installed rubies that have been registered with uru.
-func initRubies(ctx *env.Context) {
+func initRubies(ctx *env.Context) error {
 	rubies := filepath.Join(ctx.Home(), `rubies.json`)
 	if _, err := os.Stat(rubies); os.IsNotExist(err) {
-		log.Printf("[DEBUG] %s does not exist\n", rubies)
-		return
+		log.Debugf("%s does not exist\n", rubies)
+		return nil
 	}
 
 	b, err := ioutil.ReadFile(rubies)
 	if err != nil {
-		log.Printf("[DEBUG] unable to read %s\n", rubies)
-		panic("unable to read the JSON ruby registry")
+		log.Debugf("unable to read %s\n", rubies)
+		return fmt.Errorf("[ERROR] unable to read the JSON ruby registry `%s`", rubies)
 	}
 
 	err = json.Unmarshal(b, &ctx.Registry)
 	if err != nil {
-		log.Printf("[DEBUG] unable to unmarshal %s\n", rubies)
-		panic("unable to unmarshal the JSON ruby registry")
+		log.Debugf("unable to unmarshal %s\n", rubies)
+		return fmt.Errorf("[ERROR] unable to unmarshal the JSON ruby registry `%s`", rubies)
 	}
-	log.Printf("[DEBUG] === ctx.Registry.Rubies ===\n%#v", ctx.Registry.Rubies)
+	log.Tracef("=== ctx.Registry.Rubies ===\n%#v", ctx.Registry.Rubies)
+
+	return nil
 }
@@ -6,51 +6,122 @@
 package main
 
 import (
-	"log"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"bitbucket.org/jonforums/uru/internal/command"
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 func main() {
-	args := os.Args[:]
+	ctx := env.NewContext()
+	args, opts, err := parseOptions(ctx, os.Args)
+	if err != nil {
+		exit(ctx, err)
+	}
+	if !opts.logFile {
+		initLogging(opts, os.Stderr)
+	}
 
-	var needHelp bool
 	var cmd string
+	needHelp := isHelpRequest(args)
 
-	if len(args) == 1 {
-		needHelp = true
-	}
-	for _, a := range os.Args {
-		switch a {
-		case "-h", "--help":
-			needHelp = true
-		// Internal only option; if used, it must be the final cmd line option
-		case "--debug-uru":
-			log.SetOutput(os.Stderr)
-			args = os.Args[:(len(os.Args) - 1)]
-			if len(os.Args) == 2 {
-				needHelp = true
-			}
+	initHome(ctx, opts.home)
+	if opts.logFile {
+		f, err := os.OpenFile(filepath.Join(ctx.Home(), logFileName),
+			os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			exit(ctx, fmt.Errorf("[ERROR] unable to open log file\n  %v", err))
 		}
+		defer f.Close()
+		initLogging(opts, f)
+	}
+	log.Debug("initializing uru", "version", env.AppVersion, "home", ctx.Home())
+	if err := initConfig(ctx); err != nil {
+		exit(ctx, err)
+	}
+	if err := initRubies(ctx); err != nil {
+		exit(ctx, err)
 	}
-
-	log.Printf("[DEBUG] initializing uru v%s\n", env.AppVersion)
-	ctx := env.NewContext()
-	initHome(ctx)
-	initRubies(ctx)
 
 	if needHelp {
 		cmd = "help"
+		if _, n := command.CmdRouter.Walk(args[1:]); n > 0 {
+			ctx.SetCmdArgs(args[1 : n+1])
+		}
 	} else {
-		cmd = args[1]
-		if len(args) > 2 {
-			ctx.SetCmdArgs(args[2:])
+		var cmdArgs []string
+		var err error
+		cmd, cmdArgs, err = command.ExpandAlias(ctx, args[1], args[2:])
+		if err != nil {
+			exit(ctx, fmt.Errorf("[ERROR] %v", err))
+		}
+		if len(cmdArgs) > 0 {
+			ctx.SetCmdArgs(cmdArgs)
 		}
 	}
 	ctx.SetCmd(cmd)
-	log.Printf("[DEBUG] cmd = %s, args = %#v\n", cmd, ctx.CmdArgs())
+	log.Debug("dispatch", "cmd", cmd, "args", ctx.CmdArgs())
+
+	exit(ctx, command.CmdRouter.Dispatch(ctx, cmd))
+}
+
+// exit displays the error returned by a command, if any, and exits uru with
+// the error's documented exit code.
+func exit(ctx *env.Context, err error) {
+	displayError(ctx, err)
+	os.Exit(command.ExitCode(err))
+}
+
+// displayError writes the error returned by a command to stderr, keeping
+// stdout clean for the JSON, TSV and template output parsed by scripts. Errors
+// with an empty message, such as those carrying the exit code of a program run
+// by uru, aren't displayed.
+func displayError(ctx *env.Context, err error) {
+	if err != nil && err.Error() != `` {
+		fmt.Fprintln(ctx.Stderr, err)
+	}
+}
+
+// Commands passing their arguments through to the programs they run.
+var passthroughCmds = []string{"exec", "gem", "matrix", "ruby"}
+
+// isHelpRequest indicates whether the command line asks for uru's help. Help
+// options after `--`, or given to plugin commands, belong to the program being
+// run. Passthrough commands only ask for uru's help when the help option
+// directly follows the command name.
+func isHelpRequest(args []string) bool {
+	if len(args) == 1 {
+		return true
+	}
+	if isHelpOption(args[1]) {
+		return true
+	}
+
+	// plugin commands handle their own help options
+	c, err := command.CmdRouter.Handler(args[1])
+	if err != nil {
+		return false
+	}
+	for _, p := range passthroughCmds {
+		if c.Name == p {
+			return len(args) > 2 && isHelpOption(args[2])
+		}
+	}
+
+	for _, a := range args[2:] {
+		if a == "--" {
+			break
+		}
+		if isHelpOption(a) {
+			return true
+		}
+	}
+	return false
+}
 
-	command.CmdRouter.Dispatch(ctx, cmd)
+func isHelpOption(arg string) bool {
+	return arg == "-h" || arg == "--help"
 }
@@ -0,0 +1,62 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/command"
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestIsHelpRequest(t *testing.T) {
+	var tests = []struct {
+		args []string
+		want bool
+	}{
+		{[]string{`uru`}, true},
+		{[]string{`uru`, `--help`}, true},
+		{[]string{`uru`, `-h`, `ls`}, true},
+		{[]string{`uru`, `ls`, `--help`}, true},
+		{[]string{`uru`, `admin`, `gemset`, `rm`, `-h`}, true},
+		{[]string{`uru`, `exec`, `--help`}, true},
+		{[]string{`uru`, `rb`, `-h`}, true},
+		{[]string{`uru`, `ls`}, false},
+		{[]string{`uru`, `exec`, `32`, `--`, `rake`, `--help`}, false},
+		{[]string{`uru`, `matrix`, `--`, `rspec`, `--help`}, false},
+		{[]string{`uru`, `gem`, `install`, `--help`}, false},
+		{[]string{`uru`, `ruby`, `-e`, `puts 1`, `-h`}, false},
+		{[]string{`uru`, `ls`, `--`, `--help`}, false},
+		{[]string{`uru`, `not-a-cmd`, `--help`}, false},
+	}
+
+	for _, v := range tests {
+		if got := isHelpRequest(v.args); got != v.want {
+			t.Errorf("isHelpRequest() not returning correct value for `%v`\n  want: `%v`\n  got: `%v`", v.args, v.want, got)
+		}
+	}
+}
+
+func TestDisplayErrorJSON(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Options.JSON = true
+	var stdout, stderr strings.Builder
+	ctx.Stdout, ctx.Stderr = &stdout, &stderr
+	ctx.SetCmdAndArgs(`admin`, []string{`gemset`, `info`, `bogus@rails7`})
+
+	err := command.CmdRouter.Dispatch(ctx, `admin`)
+	if err == nil {
+		t.Fatal("admin gemset info not failing for an unknown ruby")
+	}
+	displayError(ctx, err)
+
+	if stdout.Len() != 0 {
+		t.Errorf("displayError() writing to stdout with --json\n  got: `%s`", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), `bogus`) {
+		t.Errorf("displayError() not writing the error to stderr\n  got: `%s`", stderr.String())
+	}
+}
@@ -0,0 +1,143 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
+)
+
+// Name of the log file written to uru's home directory by `--log-file`.
+const logFileName = `uru.log`
+
+// options are the global options that configure uru itself rather than a
+// command. The options shared with commands live in the context's Options.
+type options struct {
+	home     string
+	logLevel log.Level
+	logFile  bool
+}
+
+// parseOptions extracts uru's global options from the command line, storing
+// those shared with commands in the context's Options, and returns the
+// remaining args. Global options are given before the command, e.g.
+//
+//    uru --home /tmp/uru --yes admin rm 193p193
+//
+// except for the `--log-level LEVEL`, `--log-file` and `--debug-uru` logging
+// options which may be given in any position before a `--` marking the start
+// of a program's args. The URU_LOG, URU_LOG_FILE and NO_COLOR env vars provide
+// defaults.
+func parseOptions(ctx *env.Context, args []string) (rest []string, opts options, err error) {
+	if lvl := os.Getenv(`URU_LOG`); lvl != `` {
+		if opts.logLevel, err = log.ParseLevel(lvl); err != nil {
+			return nil, opts, fmt.Errorf("[ERROR] invalid URU_LOG env var\n  %v", err)
+		}
+	}
+	opts.logFile = os.Getenv(`URU_LOG_FILE`) != ``
+	ctx.Options.NoColor = os.Getenv(`NO_COLOR`) != ``
+
+	if len(args) > 0 {
+		rest = append(rest, args[0])
+	}
+	cmdSeen := false
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if a == `--` {
+			return append(rest, args[i:]...), opts, nil
+		}
+
+		name, value := a, ``
+		hasValue := false
+		if j := strings.Index(a, `=`); j > 0 && strings.HasPrefix(a, `--`) {
+			name, value, hasValue = a[:j], a[j+1:], true
+		}
+		optValue := func(meta string) (string, error) {
+			if !hasValue {
+				if i+1 == len(args) {
+					return ``, fmt.Errorf("[ERROR] `%s` requires a %s value", name, meta)
+				}
+				i++
+				value = args[i]
+			}
+			if value == `` {
+				return ``, fmt.Errorf("[ERROR] `%s` requires a %s value", name, meta)
+			}
+			return value, nil
+		}
+
+		noValue := func() error {
+			if hasValue {
+				return fmt.Errorf("[ERROR] `%s` takes no value", name)
+			}
+			return nil
+		}
+
+		// logging options
+		switch name {
+		case `--debug-uru`, `--log-file`:
+			if err := noValue(); err != nil {
+				return nil, opts, err
+			}
+			if name == `--log-file` {
+				opts.logFile = true
+			} else {
+				opts.logLevel = log.LevelDebug
+			}
+			continue
+		case `--log-level`:
+			lvl, err := optValue(`LEVEL`)
+			if err != nil {
+				return nil, opts, err
+			}
+			if opts.logLevel, err = log.ParseLevel(lvl); err != nil {
+				return nil, opts, fmt.Errorf("[ERROR] %v", err)
+			}
+			continue
+		}
+
+		if cmdSeen || !strings.HasPrefix(a, `-`) {
+			cmdSeen = true
+			rest = append(rest, a)
+			continue
+		}
+
+		switch name {
+		case `--quiet`, `--no-color`, `--json`, `--yes`, `--no-input`:
+			if err := noValue(); err != nil {
+				return nil, opts, err
+			}
+		}
+
+		switch name {
+		case `--home`:
+			if opts.home, err = optValue(`DIR`); err != nil {
+				return nil, opts, err
+			}
+		case `--shell`:
+			if ctx.Options.Shell, err = optValue(`NAME`); err != nil {
+				return nil, opts, err
+			}
+		case `--quiet`:
+			ctx.Options.Quiet = true
+		case `--no-color`:
+			ctx.Options.NoColor = true
+		case `--json`:
+			ctx.Options.JSON = true
+		case `--yes`:
+			ctx.Options.Yes = true
+		case `--no-input`:
+			ctx.Options.NoInput = true
+		default:
+			// not a global option, e.g. `--help`
+			rest = append(rest, a)
+		}
+	}
+
+	return
+}
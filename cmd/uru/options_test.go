@@ -0,0 +1,94 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
+)
+
+func TestParseOptions(t *testing.T) {
+	t.Setenv(`URU_LOG`, ``)
+	t.Setenv(`URU_LOG_FILE`, ``)
+	t.Setenv(`NO_COLOR`, ``)
+
+	var tests = []struct {
+		args    []string
+		rest    []string
+		opts    options
+		ctxOpts env.Options
+	}{
+		{[]string{`uru`, `ls`}, []string{`uru`, `ls`}, options{}, env.Options{}},
+		{[]string{`uru`, `--home`, `/tmp/uru`, `--quiet`, `--yes`, `admin`, `rm`, `223`},
+			[]string{`uru`, `admin`, `rm`, `223`}, options{home: `/tmp/uru`}, env.Options{Quiet: true, Yes: true}},
+		{[]string{`uru`, `--json`, `--no-color`, `--shell=fish`, `ls`, `--verbose`},
+			[]string{`uru`, `ls`, `--verbose`}, options{}, env.Options{JSON: true, NoColor: true, Shell: `fish`}},
+		{[]string{`uru`, `gem`, `install`, `--quiet`, `--log-level`, `debug`, `rake`},
+			[]string{`uru`, `gem`, `install`, `--quiet`, `rake`}, options{logLevel: log.LevelDebug}, env.Options{}},
+		{[]string{`uru`, `exec`, `223`, `--log-file`, `--`, `ruby`, `--log-level=trace`},
+			[]string{`uru`, `exec`, `223`, `--`, `ruby`, `--log-level=trace`}, options{logFile: true}, env.Options{}},
+		{[]string{`uru`, `--no-input`, `use`, `2`},
+			[]string{`uru`, `use`, `2`}, options{}, env.Options{NoInput: true}},
+		{[]string{`uru`, `--help`, `ls`, `--debug-uru`},
+			[]string{`uru`, `--help`, `ls`}, options{logLevel: log.LevelDebug}, env.Options{}},
+	}
+
+	for _, v := range tests {
+		ctx := env.NewContext()
+		ctx.Options = env.Options{}
+		rest, opts, err := parseOptions(ctx, v.args)
+		if err != nil {
+			t.Errorf("parseOptions() returned error for `%v`: %v", v.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(rest, v.rest) {
+			t.Errorf("parseOptions() not returning correct args\n  want: `%v`\n  got: `%v`", v.rest, rest)
+		}
+		if opts != v.opts {
+			t.Errorf("parseOptions() not returning correct options\n  want: `%+v`\n  got: `%+v`", v.opts, opts)
+		}
+		if ctx.Options != v.ctxOpts {
+			t.Errorf("parseOptions() not setting correct context options\n  want: `%+v`\n  got: `%+v`",
+				v.ctxOpts, ctx.Options)
+		}
+	}
+
+	invalid := [][]string{
+		{`uru`, `--home`},
+		{`uru`, `--home=`, `ls`},
+		{`uru`, `--quiet=yes`, `ls`},
+		{`uru`, `ls`, `--log-level`, `loud`},
+	}
+	for _, args := range invalid {
+		if _, _, err := parseOptions(env.NewContext(), args); err == nil {
+			t.Errorf("parseOptions() not returning error for `%v`", args)
+		}
+	}
+}
+
+func TestParseOptionsEnv(t *testing.T) {
+	t.Setenv(`URU_LOG`, `warn`)
+	t.Setenv(`URU_LOG_FILE`, `1`)
+	t.Setenv(`NO_COLOR`, `1`)
+
+	ctx := env.NewContext()
+	_, opts, err := parseOptions(ctx, []string{`uru`, `ls`})
+	if err != nil {
+		t.Fatalf("parseOptions() returned error: %v", err)
+	}
+	if want := (options{logLevel: log.LevelWarn, logFile: true}); opts != want {
+		t.Errorf("parseOptions() not using env var defaults\n  want: `%+v`\n  got: `%+v`", want, opts)
+	}
+	if !ctx.Options.NoColor {
+		t.Error("parseOptions() not honoring the NO_COLOR env var")
+	}
+
+	t.Setenv(`URU_LOG`, `loud`)
+	if _, _, err := parseOptions(env.NewContext(), []string{`uru`, `ls`}); err == nil {
+		t.Error("parseOptions() not returning error for an invalid URU_LOG env var")
+	}
+}
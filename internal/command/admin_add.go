@@ -4,6 +4,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -25,67 +26,58 @@ var adminAddCmd *Command = &Command{
 	Usage:   "admin add DIR [--tag TAG] | --recurse DIR [--dirtag] | system",
 	Eg:      `admin add C:\Apps\rubies\ruby-2.1\bin`,
 	Short:   "register an existing ruby installation",
-	Run:     adminAdd,
+	Long: `Registers an existing ruby installation with uru, identifying it by its bin
+dir, i.e. the dir containing the ruby, jruby or rbx executable. Uru runs the
+ruby to learn its version and default gem home, and tags it with a label
+generated from its version unless given --tag.
+
+The special 'system' location registers the first ruby found on PATH, such as
+an OS packaged ruby. With --recurse, every DIR/*/bin dir containing a ruby is
+registered, for example all the rubies installed by ruby-install:
+
+    uru admin add --recurse ~/.rubies --dirtag
+
+Already registered rubies are skipped.`,
+	Flags: []Flag{
+		{Name: `tag`, Value: `TAG`, Usage: "register the ruby with the TAG label"},
+		{Name: `recurse`, Value: `DIR`, Usage: "register the rubies in each DIR/*/bin dir"},
+		{Name: `dirtag`, Usage: "tag rubies registered with --recurse by their dir name"},
+	},
+	Run: adminAdd,
 }
 
 func init() {
 	adminRouter.Handle(adminAddCmd.Aliases, adminAddCmd)
 }
 
-func adminAdd(ctx *env.Context) {
-	argsLen := 0
+func adminAdd(ctx *env.Context) error {
 	cmdArgs := ctx.CmdArgs()
+	tagAlias, baseDir := ctx.Flag(`tag`), ctx.Flag(`recurse`)
+	dirTag := ctx.IsFlagSet(`dirtag`)
 
-	if argsLen = len(cmdArgs); argsLen == 0 {
-		fmt.Println("[ERROR] must specify a ruby installation or `system`.")
-		os.Exit(1)
-	}
-
-	var tagAlias, baseDir string
-	var dirTag bool
-	for i, v := range ctx.CmdArgs() {
-		if v == `--tag` {
-			if i < argsLen-1 {
-				tagAlias = cmdArgs[i+1]
-				break
-			} else {
-				fmt.Println("[ERROR] invalid `admin add --tag TAG` invocation.")
-				os.Exit(1)
-			}
-		}
-		if v == `--recurse` {
-			if i < argsLen-1 {
-				baseDir = cmdArgs[i+1]
-			} else {
-				fmt.Println("[ERROR] invalid `admin add --recurse BASE_DIR` invocation.")
-				os.Exit(1)
-			}
-		}
-		if v == `--dirtag` {
-			dirTag = true
-		}
+	if len(cmdArgs) == 0 && baseDir == `` {
+		return errors.New("[ERROR] must specify a ruby installation or `system`.")
 	}
 
 	if baseDir != `` {
 		// register ruby installations in subdirs of given base dir
 		loc, err := filepath.Abs(baseDir)
 		if err != nil {
-			fmt.Println("[ERROR] unable to determine absolute ruby base dir path.")
-			os.Exit(1)
+			return errors.New("[ERROR] unable to determine absolute ruby base dir path.")
 		}
 
 		subdirs, err := filepath.Glob(filepath.Join(loc, `*`, `bin`))
 		if subdirs == nil || err != nil {
-			fmt.Println("[ERROR] unable to determine ruby base dir subdirs.")
-			os.Exit(1)
+			return errors.New("[ERROR] unable to determine ruby base dir subdirs.")
 		}
 
+		failed := false
 	SubdirLoop:
 		for _, bindir := range subdirs {
 			for _, i := range ctx.Registry.Rubies {
 				// XXX comparison of string paths too fragile?
 				if i.Home == bindir {
-					fmt.Printf("---> Skipping. `%s` is already registered\n", bindir)
+					ctx.Infof("---> Skipping. `%s` is already registered\n", bindir)
 					continue SubdirLoop
 				}
 			}
@@ -96,7 +88,14 @@ func adminAdd(ctx *env.Context) {
 				tagAlias = ``
 			}
 
-			registerRuby(ctx, bindir, tagAlias, MULTI_REGISTRATION)
+			// keep registering the remaining rubies, reporting each failure
+			if err := registerRuby(ctx, bindir, tagAlias, MULTI_REGISTRATION); err != nil {
+				fmt.Fprintln(ctx.Stderr, err)
+				failed = true
+			}
+		}
+		if failed {
+			return exitStatus(ExitError)
 		}
 	} else {
 		// register ruby installation in given bin directory
@@ -105,24 +104,27 @@ func adminAdd(ctx *env.Context) {
 		if loc != `system` {
 			loc, err = filepath.Abs(loc)
 			if err != nil {
-				fmt.Println("[ERROR] unable to determine absolute ruby bindir path.")
-				os.Exit(1)
+				return errors.New("[ERROR] unable to determine absolute ruby bindir path.")
 			}
 
 			for _, i := range ctx.Registry.Rubies {
 				// XXX comparison of string paths too fragile?
 				if i.Home == loc {
-					fmt.Printf("---> Skipping. `%s` is already registered\n", loc)
-					return
+					ctx.Infof("---> Skipping. `%s` is already registered\n", loc)
+					return nil
 				}
 			}
 		}
 
-		registerRuby(ctx, loc, tagAlias, SINGLE_REGISTRATION)
+		return registerRuby(ctx, loc, tagAlias, SINGLE_REGISTRATION)
 	}
+
+	return nil
 }
 
-func registerRuby(ctx *env.Context, location string, tagAlias string, regType int) {
+// registerRuby registers the ruby installed in the location, returning an error
+// if the ruby can't be registered.
+func registerRuby(ctx *env.Context, location string, tagAlias string, regType int) error {
 	var rbPath, ext string
 	if runtime.GOOS == `windows` {
 		ext = `.exe`
@@ -148,22 +150,19 @@ func registerRuby(ctx *env.Context, location string, tagAlias string, regType in
 			}
 		}
 		if rbPath == `` {
-			fmt.Printf("---> Unable to find a known ruby at `%s`\n", location)
-			return
+			return fmt.Errorf("---> Unable to find a known ruby at `%s`", location)
 		}
 	}
 
 	tagHash, rbInfo, err := env.RubyInfo(ctx, rbPath)
 	if err != nil {
-		fmt.Printf("---> Unable to register `%s` due to missing ruby info\n", rbPath)
-		return
+		return fmt.Errorf("---> Unable to register `%s` due to missing ruby info", rbPath)
 	}
 
 	// set the tag alias if given and it does not conflict with a uru reserved label
 	if tagAlias != `` {
 		if rsvd, word := isTagLabelReserved(tagAlias); rsvd == true {
-			fmt.Printf("---> Tag label `%s` conflicts with reserved `%s`. Try again\n", tagAlias, word)
-			os.Exit(1)
+			return fmt.Errorf("---> Tag label `%s` conflicts with reserved `%s`. Try again", tagAlias, word)
 		} else {
 			rbInfo.TagLabel = tagAlias
 		}
@@ -186,7 +185,7 @@ func registerRuby(ctx *env.Context, location string, tagAlias string, regType in
 				if tagAlias != `` {
 					rbInfo.TagLabel = tagAlias
 				} else {
-					fmt.Printf(`
+					return fmt.Errorf(`
 ---> So sorry, but I'm not able to register the following ruby
 --->
 --->   %s
@@ -198,7 +197,6 @@ func registerRuby(ctx *env.Context, location string, tagAlias string, regType in
 --->   %s admin add DIR --tag TAG
 --->
 ---> where TAG is 12 characters or less.`, location, env.AppName)
-					os.Exit(1)
 				}
 			}
 		}
@@ -214,10 +212,10 @@ func registerRuby(ctx *env.Context, location string, tagAlias string, regType in
 
 	// persist the new and existing registered rubies to the filesystem
 	// XXX marshall for each --recurse invocation?
-	err = ctx.Registry.Marshal(ctx)
-	if err != nil {
-		fmt.Printf("---> Failed to register `%s`, try again\n", rbPath)
-	} else {
-		fmt.Printf("---> Registered %s at `%s` as `%s`\n", rbInfo.Exe, rbInfo.Home, rbInfo.TagLabel)
+	if err = ctx.Registry.Marshal(ctx); err != nil {
+		return fmt.Errorf("---> Failed to register `%s`, try again", rbPath)
 	}
+	ctx.Infof("---> Registered %s at `%s` as `%s`\n", rbInfo.Exe, rbInfo.Home, rbInfo.TagLabel)
+
+	return nil
 }
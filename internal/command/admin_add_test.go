@@ -0,0 +1,52 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestAdminAddFailures(t *testing.T) {
+	base := t.TempDir()
+	os.MkdirAll(filepath.Join(base, `ruby-3.2.2`, `bin`), 0750)
+
+	bindir := filepath.Join(base, `ruby-3.2.2`, `bin`)
+
+	var tests = []struct {
+		args   []string
+		errMsg string // expected in the returned error
+		errOut string // expected in the error output
+	}{
+		{[]string{`add`, bindir}, `Unable to find a known ruby`, ``},
+		{[]string{`add`, `--recurse`, base}, ``, `Unable to find a known ruby`},
+	}
+
+	for _, v := range tests {
+		ctx := env.NewContext()
+		ctx.SetHome(t.TempDir())
+		stderr := &strings.Builder{}
+		ctx.Stdout, ctx.Stderr = &strings.Builder{}, stderr
+		ctx.SetCmdAndArgs(`admin`, v.args)
+
+		err := CmdRouter.Dispatch(ctx, `admin`)
+		if got := ExitCode(err); got != ExitError {
+			t.Errorf("`admin %v` not returning correct exit code\n  want: `%d`\n  got: `%d`",
+				v.args, ExitError, got)
+		}
+		if err == nil || !strings.Contains(err.Error(), v.errMsg) {
+			t.Errorf("`admin %v` not returning correct error\n  want: `%s`\n  got: `%v`", v.args, v.errMsg, err)
+		}
+		if !strings.Contains(stderr.String(), v.errOut) {
+			t.Errorf("`admin %v` not reporting the dir without a ruby\n  got: `%s`", v.args, stderr.String())
+		}
+		if len(ctx.Registry.Rubies) != 0 {
+			t.Errorf("`admin %v` registering a ruby", v.args)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var adminCompletionCmd *Command = &Command{
+	Name:    "completion",
+	Aliases: []string{"completion"},
+	Usage:   "admin completion bash|zsh|fish|powershell",
+	Eg:      "admin completion bash",
+	Short:   "generate a shell completion script",
+	Long: `Writes a tab completion script for the given shell to stdout. The script
+completes commands, admin sub-commands, flags, registered ruby tags and
+gemset names by asking uru for the candidates, so completions always reflect
+the currently registered rubies. Load the script from your shell's startup
+file, for example:
+
+    source <(uru_rt admin completion bash)
+    uru_rt admin completion fish > ~/.config/fish/completions/uru.fish`,
+	Flags: []Flag{},
+	Run:   adminCompletion,
+}
+
+func init() {
+	adminRouter.Handle(adminCompletionCmd.Aliases, adminCompletionCmd)
+}
+
+var completionShells = []string{`bash`, `zsh`, `fish`, `powershell`}
+
+// shell completion script templates; each completes the `uru` shell wrapper and
+// `uru_rt` using the candidates listed by `uru_rt __complete`.
+var bashCompletion = `# uru bash completion; autogenerated by ` + "`uru admin completion bash`" + `
+#
+# Load it in the current shell, or from ~/.bashrc, with
+#
+#   source <(uru_rt admin completion bash)
+
+_uru_complete()
+{
+  local IFS=$'\n'
+  COMPREPLY=( $(uru_rt __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1) )
+}
+complete -o default -F _uru_complete uru uru_rt
+`
+
+var zshCompletion = `#compdef uru uru_rt
+# uru zsh completion; autogenerated by ` + "`uru admin completion zsh`" + `
+#
+# Load it in the current shell, or from ~/.zshrc after compinit, with
+#
+#   source <(uru_rt admin completion zsh)
+
+_uru() {
+  local -a candidates
+  local line name
+  for line in "${(@f)$(uru_rt __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+    [[ -z $line ]] && continue
+    name=${line%%$'\t'*}
+    candidates+=("${name//:/\\:}:${line#*$'\t'}")
+  done
+  if (( ${#candidates} )); then
+    _describe 'uru' candidates
+  else
+    _files
+  fi
+}
+compdef _uru uru uru_rt
+`
+
+var fishCompletion = `# uru fish completion; autogenerated by ` + "`uru admin completion fish`" + `
+#
+# Load it in the current shell with
+#
+#   uru_rt admin completion fish | source
+#
+# or save it as ~/.config/fish/completions/uru.fish
+
+function __uru_complete
+  set -l words (commandline -opc)
+  set -l cur (commandline -ct)
+  uru_rt __complete $words[2..-1] "$cur" 2>/dev/null
+end
+complete -c uru -f -a '(__uru_complete)'
+complete -c uru_rt -f -a '(__uru_complete)'
+`
+
+var powershellCompletion = `# uru PowerShell completion; autogenerated by ` + "`uru admin completion powershell`" + `
+#
+# Load it in the current session, or from your $PROFILE, with
+#
+#   uru_rt admin completion powershell | Out-String | Invoke-Expression
+
+Register-ArgumentCompleter -Native -CommandName uru, uru_rt -ScriptBlock {
+  param($wordToComplete, $commandAst, $cursorPosition)
+
+  $words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+    Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+    ForEach-Object { $_.ToString() })
+  $cword = $words.Count
+  if ($wordToComplete -ne '') { $cword -= 1 }
+
+  uru_rt __complete --cword $cword @words 2>$null | ForEach-Object {
+    $name, $desc = $_ -split "` + "`" + `t", 2
+    if (-not $desc) { $desc = $name }
+    [System.Management.Automation.CompletionResult]::new($name, $name, 'ParameterValue', $desc)
+  }
+}
+`
+
+// Implements the functionality for the user visible command
+//
+//    uru admin completion bash|zsh|fish|powershell
+//
+// which writes a completion script for the given shell to stdout. The scripts
+// complete commands, admin sub-commands, flags, registered ruby tag labels, and
+// gemset names by calling the hidden `uru_rt __complete` command, so
+// completions always reflect the currently registered rubies.
+func adminCompletion(ctx *env.Context) error {
+	cmdArgs := ctx.CmdArgs()
+	if len(cmdArgs) != 1 {
+		return fmt.Errorf("[ERROR] invalid `admin completion %s` invocation.", strings.Join(completionShells, `|`))
+	}
+
+	switch cmdArgs[0] {
+	case `bash`:
+		fmt.Fprint(ctx.Stdout, bashCompletion)
+	case `zsh`:
+		fmt.Fprint(ctx.Stdout, zshCompletion)
+	case `fish`:
+		fmt.Fprint(ctx.Stdout, fishCompletion)
+	case `powershell`, `pwsh`:
+		fmt.Fprint(ctx.Stdout, powershellCompletion)
+	default:
+		return fmt.Errorf("[ERROR] unknown shell `%s`; use one of %s.", cmdArgs[0], strings.Join(completionShells, `, `))
+	}
+
+	return nil
+}
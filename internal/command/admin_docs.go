@@ -0,0 +1,338 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
+)
+
+var adminDocsCmd *Command = &Command{
+	Name:    "docs",
+	Aliases: []string{"docs"},
+	Usage:   "admin docs [--format man|markdown] DIR",
+	Eg:      "admin docs --format markdown doc",
+	Short:   "generate man pages or a markdown command reference",
+	Long: `Writes the documentation of every uru command, generated from the same
+descriptions displayed by 'uru help', to DIR. The default man format writes
+an uru.1 overview man page plus a man page per command, e.g.
+uru-admin-gemset-rm.1, while the markdown format writes a single uru.md
+command reference. DIR is created if it doesn't exist.`,
+	Flags: []Flag{
+		{Name: `format`, Value: `FORMAT`, Usage: "write `man` pages, the default, or a `markdown` reference"},
+	},
+	Run: adminDocs,
+}
+
+func init() {
+	adminRouter.Handle(adminDocsCmd.Aliases, adminDocsCmd)
+}
+
+var docsFormats = []string{`man`, `markdown`}
+
+func adminDocs(ctx *env.Context) error {
+	cmdArgs := ctx.CmdArgs()
+	if len(cmdArgs) != 1 {
+		return errors.New("[ERROR] invalid `admin docs [--format man|markdown] DIR` invocation.")
+	}
+	dir := cmdArgs[0]
+
+	docs := documentedCommands(CmdRouter, nil, false)
+	files := make(map[string]string)
+	switch format := ctx.Flag(`format`); format {
+	case ``, `man`:
+		files[manPageName(nil)] = manIndexPage(docs)
+		for _, d := range docs {
+			files[manPageName(d.path)] = manPage(d)
+		}
+	case `markdown`:
+		files[fmt.Sprintf("%s.md", env.AppName)] = markdownReference(docs)
+	default:
+		return fmt.Errorf("[ERROR] unknown docs format `%s`; use %s.", format, strings.Join(docsFormats, ` or `))
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Warn("unable to create docs dir", "dir", dir, "err", err)
+		return fmt.Errorf("[ERROR] unable to create docs dir `%s`", dir)
+	}
+	for name, doc := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(doc), 0644); err != nil {
+			log.Warn("unable to write docs", "file", name, "err", err)
+			return fmt.Errorf("[ERROR] unable to write `%s` to `%s`", name, dir)
+		}
+	}
+	ctx.Infof("---> wrote %d docs files to `%s`\n", len(files), dir)
+
+	return nil
+}
+
+// docCommand is a documented command along with the path of command names
+// leading to it, e.g. `admin gemset rm`.
+type docCommand struct {
+	path []string
+	cmd  *Command
+}
+
+// title returns the user visible command line of the command, e.g.
+// `uru admin gemset rm`.
+func (d docCommand) title() string {
+	return commandPath(d.path)
+}
+
+// documentedCommands returns the commands registered with a router and,
+// recursively, with the child routers of its commands, each command followed
+// by its sub-commands. Commands are sorted by name. Hidden commands are only
+// included when hidden is true.
+func documentedCommands(r *Router, path []string, hidden bool) (docs []docCommand) {
+	seen := make(map[*Command]bool)
+	var cmds []*Command
+	for _, m := range []map[string]*Command{r.handlers, r.commands} {
+		for _, c := range m {
+			if !seen[c] && (hidden || !c.Hidden) {
+				seen[c] = true
+				cmds = append(cmds, c)
+			}
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+
+	for _, c := range cmds {
+		p := append(path[:len(path):len(path)], c.Name)
+		docs = append(docs, docCommand{path: p, cmd: c})
+		if c.Subcommands != nil {
+			docs = append(docs, documentedCommands(c.Subcommands, p, hidden)...)
+		}
+	}
+
+	return
+}
+
+// commandsAtDepth returns the documented commands whose path has depth names,
+// e.g. the top-level commands for a depth of 1.
+func commandsAtDepth(docs []docCommand, depth int) (cmds []docCommand) {
+	for _, d := range docs {
+		if len(d.path) == depth {
+			cmds = append(cmds, d)
+		}
+	}
+	return
+}
+
+// docBlock is a paragraph of a command's Long description, or a block of its
+// lines indented by 4 spaces, such as example command lines, that must be
+// displayed verbatim.
+type docBlock struct {
+	lines    []string
+	verbatim bool
+}
+
+// longBlocks splits a command's Long description into its blank line separated
+// paragraphs and verbatim blocks.
+func longBlocks(long string) (blocks []docBlock) {
+	var cur *docBlock
+	for _, l := range strings.Split(strings.TrimSpace(long), "\n") {
+		if strings.TrimSpace(l) == `` {
+			cur = nil
+			continue
+		}
+		verbatim := strings.HasPrefix(l, `    `)
+		if cur == nil || cur.verbatim != verbatim {
+			blocks = append(blocks, docBlock{verbatim: verbatim})
+			cur = &blocks[len(blocks)-1]
+		}
+		if verbatim {
+			l = l[4:]
+		}
+		cur.lines = append(cur.lines, l)
+	}
+
+	return
+}
+
+// manPageName returns the file name of the man page for the command path,
+// e.g. `uru-admin-gemset-rm.1`, or of the overview man page for an empty path.
+func manPageName(path []string) string {
+	return fmt.Sprintf("%s.1", strings.ToLower(strings.Join(append([]string{env.AppName}, path...), `-`)))
+}
+
+var manEscaper = strings.NewReplacer(`\`, `\e`, `-`, `\-`)
+
+// manText escapes text for use in a roff man page, preventing lines starting
+// with `.` or `'` from being read as roff requests.
+func manText(s string) string {
+	s = manEscaper.Replace(s)
+	if strings.HasPrefix(s, `.`) || strings.HasPrefix(s, `'`) {
+		s = `\&` + s
+	}
+	return s
+}
+
+// manHeader writes the title and name sections of a man page.
+func manHeader(b *strings.Builder, name, short string) {
+	fmt.Fprintf(b, ".TH \"%s\" \"1\" \"\" \"%s %s\" \"Uru Manual\"\n",
+		manText(strings.ToUpper(name)), env.AppName, env.AppVersion)
+	fmt.Fprintf(b, ".SH NAME\n%s \\- %s\n", manText(name), manText(short))
+}
+
+// manFlags writes the flags as a man page section.
+func manFlags(b *strings.Builder, section string, flags []Flag) {
+	if len(flags) == 0 {
+		return
+	}
+	fmt.Fprintf(b, ".SH %s\n", section)
+	for _, f := range flags {
+		usage := f.Usage
+		if f.Repeated {
+			usage += " (repeatable)"
+		}
+		fmt.Fprintf(b, ".TP\n.B %s\n%s\n", manText(f.String()), manText(usage))
+	}
+}
+
+// manCommands writes the summaries of the commands as a man page section,
+// referring to each command's man page.
+func manCommands(b *strings.Builder, section string, docs []docCommand) {
+	if len(docs) == 0 {
+		return
+	}
+	fmt.Fprintf(b, ".SH %s\n", section)
+	for _, d := range docs {
+		fmt.Fprintf(b, ".TP\n.BR %s (1)\n%s\n",
+			manText(strings.TrimSuffix(manPageName(d.path), `.1`)), manText(d.cmd.Short))
+	}
+}
+
+// manIndexPage returns the uru.1 overview man page listing uru's top-level
+// commands and global options.
+func manIndexPage(docs []docCommand) string {
+	var b strings.Builder
+	manHeader(&b, env.AppName, "use multiple rubies on Linux, OS X and Windows")
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n[OPTIONS] CMD ARGS...\n", env.AppName)
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString("Uru is a lightweight, multi\\-platform command line tool that helps you use\n")
+	b.WriteString("the multiple rubies installed on your system.\n")
+	b.WriteString("Run 'uru help CMD', or see the command's man page, for the help of a command.\n")
+
+	manCommands(&b, `COMMANDS`, commandsAtDepth(docs, 1))
+	manFlags(&b, `OPTIONS`, GlobalFlags)
+
+	return b.String()
+}
+
+// manPage returns the man page of a command.
+func manPage(d docCommand) string {
+	var b strings.Builder
+	c := d.cmd
+	manHeader(&b, strings.Join(append([]string{env.AppName}, d.path...), `-`), c.Short)
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n%s\n", env.AppName, manText(c.Usage))
+
+	b.WriteString(".SH DESCRIPTION\n")
+	for i, blk := range longBlocks(c.Long) {
+		if i > 0 {
+			b.WriteString(".PP\n")
+		}
+		if blk.verbatim {
+			b.WriteString(".RS 4\n.nf\n")
+		}
+		for _, l := range blk.lines {
+			fmt.Fprintf(&b, "%s\n", manText(l))
+		}
+		if blk.verbatim {
+			b.WriteString(".fi\n.RE\n")
+		}
+	}
+	if len(c.Aliases) > 1 {
+		fmt.Fprintf(&b, ".PP\nAliases: %s\n", manText(strings.Join(c.Aliases, `, `)))
+	}
+
+	manFlags(&b, `OPTIONS`, c.Flags)
+	if c.Subcommands != nil {
+		subs := documentedCommands(c.Subcommands, d.path, false)
+		manCommands(&b, `COMMANDS`, commandsAtDepth(subs, len(d.path)+1))
+	}
+	fmt.Fprintf(&b, ".SH EXAMPLE\n.RS 4\n.nf\n%s %s\n.fi\n.RE\n", env.AppName, manText(c.Eg))
+
+	parent := manPageName(d.path[:len(d.path)-1])
+	fmt.Fprintf(&b, ".SH SEE ALSO\n.BR %s (1)\n", manText(strings.TrimSuffix(parent, `.1`)))
+
+	return b.String()
+}
+
+// markdownAnchor returns the anchor GitHub and Bitbucket generate for a
+// markdown heading, e.g. `uru-admin-gemset-rm` for `uru admin gemset rm`.
+func markdownAnchor(heading string) string {
+	return strings.ToLower(strings.Replace(heading, ` `, `-`, -1))
+}
+
+// markdownFlags writes the flags as a markdown list.
+func markdownFlags(b *strings.Builder, title string, flags []Flag) {
+	if len(flags) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s:\n\n", title)
+	for _, f := range flags {
+		usage := f.Usage
+		if f.Repeated {
+			usage += " (repeatable)"
+		}
+		fmt.Fprintf(b, "* `%s` %s\n", f.String(), usage)
+	}
+	b.WriteString("\n")
+}
+
+// markdownCommands writes the summaries of the commands as a markdown list
+// linking to each command's section.
+func markdownCommands(b *strings.Builder, docs []docCommand) {
+	for _, d := range docs {
+		fmt.Fprintf(b, "* [`%s`](#%s) %s\n", d.title(), markdownAnchor(d.title()), d.cmd.Short)
+	}
+	b.WriteString("\n")
+}
+
+// markdownReference returns the markdown reference of uru's global options
+// and commands.
+func markdownReference(docs []docCommand) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s %s command reference\n\n", env.AppName, env.AppVersion)
+	fmt.Fprintf(&b, "Usage: `%s [options] CMD ARG...`\n\n", env.AppName)
+	markdownCommands(&b, commandsAtDepth(docs, 1))
+	markdownFlags(&b, `Global options`, GlobalFlags)
+
+	for _, d := range docs {
+		c := d.cmd
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", d.title(), c.Short)
+		fmt.Fprintf(&b, "~~~ sh\n%s %s\n~~~\n\n", env.AppName, c.Usage)
+		for _, blk := range longBlocks(c.Long) {
+			if blk.verbatim {
+				fmt.Fprintf(&b, "~~~ sh\n%s\n~~~\n\n", strings.Join(blk.lines, "\n"))
+				continue
+			}
+			fmt.Fprintf(&b, "%s\n\n", strings.Join(blk.lines, "\n"))
+		}
+		if len(c.Aliases) > 1 {
+			quoted := make([]string, len(c.Aliases))
+			for i, a := range c.Aliases {
+				quoted[i] = fmt.Sprintf("`%s`", a)
+			}
+			fmt.Fprintf(&b, "Aliases: %s\n\n", strings.Join(quoted, `, `))
+		}
+		markdownFlags(&b, `Flags`, c.Flags)
+		if c.Subcommands != nil {
+			b.WriteString("Sub-commands:\n\n")
+			subs := documentedCommands(c.Subcommands, d.path, false)
+			markdownCommands(&b, commandsAtDepth(subs, len(d.path)+1))
+		}
+		fmt.Fprintf(&b, "Example:\n\n~~~ sh\n%s %s\n~~~\n\n", env.AppName, c.Eg)
+	}
+
+	return b.String()
+}
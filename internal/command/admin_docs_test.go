@@ -0,0 +1,136 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestCommandsDocumented(t *testing.T) {
+	docs := documentedCommands(CmdRouter, nil, true)
+	if len(docs) == 0 {
+		t.Fatal("documentedCommands() not returning the registered commands")
+	}
+
+	for _, d := range docs {
+		if strings.TrimSpace(d.cmd.Long) == `` {
+			t.Errorf("`%s` command has no Long description", d.title())
+		}
+		if strings.TrimSpace(d.cmd.Eg) == `` {
+			t.Errorf("`%s` command has no example", d.title())
+		}
+		for _, l := range strings.Split(d.cmd.Long, "\n") {
+			if len(l) > 78 {
+				t.Errorf("`%s` command's Long description has a line longer than 78 chars\n  got: `%s`", d.title(), l)
+			}
+		}
+	}
+}
+
+func TestDocumentedCommands(t *testing.T) {
+	var got []string
+	for _, d := range documentedCommands(CmdRouter, nil, false) {
+		got = append(got, strings.Join(d.path, ` `))
+	}
+
+	for _, want := range []string{`TAG`, `admin gemset rm`, `help`, `version`} {
+		found := false
+		for _, g := range got {
+			found = found || g == want
+		}
+		if !found {
+			t.Errorf("documentedCommands() missing the `%s` command\n  got: `%v`", want, got)
+		}
+	}
+	for i, g := range got {
+		if g == `__complete` {
+			t.Errorf("documentedCommands() including the hidden `%s` command", g)
+		}
+		if g == `admin gemset` && (i+1 == len(got) || !strings.HasPrefix(got[i+1], `admin gemset `)) {
+			t.Errorf("documentedCommands() not listing sub-commands after their command\n  got: `%v`", got)
+		}
+	}
+}
+
+func TestLongBlocks(t *testing.T) {
+	long := "First paragraph\nstill first.\n\n    uru ls\n    uru 32\nAfter the example.\n\nLast."
+	want := []docBlock{
+		{lines: []string{`First paragraph`, `still first.`}},
+		{lines: []string{`uru ls`, `uru 32`}, verbatim: true},
+		{lines: []string{`After the example.`}},
+		{lines: []string{`Last.`}},
+	}
+
+	if got := longBlocks(long); !reflect.DeepEqual(got, want) {
+		t.Errorf("longBlocks() not returning correct value\n  want: `%v`\n  got: `%v`", want, got)
+	}
+}
+
+func TestManText(t *testing.T) {
+	var tests = []struct {
+		text string
+		want string
+	}{
+		{`--dry-run`, `\-\-dry\-run`},
+		{`C:\Apps\bin`, `C:\eApps\ebin`},
+		{`.ruby-version files`, `\&.ruby\-version files`},
+		{`'auto' tag`, `\&'auto' tag`},
+	}
+
+	for _, v := range tests {
+		if got := manText(v.text); got != v.want {
+			t.Errorf("manText() not returning correct value for `%s`\n  want: `%v`\n  got: `%v`", v.text, v.want, got)
+		}
+	}
+}
+
+func TestAdminDocs(t *testing.T) {
+	run := func(args ...string) (string, error) {
+		ctx := env.NewContext()
+		ctx.Stdout = &strings.Builder{}
+		dir := t.TempDir()
+		ctx.SetCmdAndArgs(`admin`, append(append([]string{`docs`}, args...), dir))
+		return dir, CmdRouter.Dispatch(ctx, `admin`)
+	}
+
+	dir, err := run()
+	if err != nil {
+		t.Fatalf("admin docs returned error: %v", err)
+	}
+	for _, name := range []string{`uru.1`, `uru-admin-gemset-rm.1`, `uru-tag.1`} {
+		if _, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil {
+			t.Errorf("admin docs not writing the `%s` man page: %v", name, err)
+		}
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, `uru-admin-gemset-rm.1`))
+	for _, want := range []string{".TH \"URU\\-ADMIN\\-GEMSET\\-RM\" \"1\"", ".SH DESCRIPTION\n", "\\-\\-dry\\-run", ".BR uru\\-admin\\-gemset (1)"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("admin docs man page missing `%s`\n  got: `%s`", want, b)
+		}
+	}
+
+	dir, err = run(`--format`, `markdown`)
+	if err != nil {
+		t.Fatalf("admin docs --format markdown returned error: %v", err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, `uru.md`))
+	if err != nil {
+		t.Fatalf("admin docs not writing the markdown reference: %v", err)
+	}
+	for _, want := range []string{"## uru admin gemset rm\n", "* [`uru admin gemset rm`](#uru-admin-gemset-rm)", gemsetRmCmd.Long} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("admin docs markdown reference missing `%s`", want)
+		}
+	}
+
+	if _, err = run(`--format`, `html`); err == nil || !strings.Contains(err.Error(), `unknown docs format`) {
+		t.Errorf("admin docs not failing for an unknown format\n  got: `%v`", err)
+	}
+}
@@ -0,0 +1,381 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var gemsRouter *Router = NewRouter(nil)
+
+var adminGemsCmd *Command = &Command{
+	Name:    "gems",
+	Aliases: []string{"gems"},
+	Usage:   "admin gems SUBCMD ARGS",
+	Eg:      "admin gems migrate 322p53 323p100",
+	Short:   "copy installed gems between rubies",
+	Long: `The gems sub-commands manage the gems installed in the gem homes and gemsets
+of registered rubies.`,
+	Subcommands: gemsRouter,
+}
+
+var gemsMigrateCmd *Command = &Command{
+	Name:    "migrate",
+	Aliases: []string{"migrate"},
+	Usage:   "admin gems migrate [--jobs N] [--batch N] [--dry-run] FROM TO",
+	Eg:      "admin gems migrate 322p53 323p100",
+	Short:   "install the gems of one ruby into another",
+	Long: `Installs the gems, and gem versions, installed in the FROM ruby's gem home
+into the TO ruby's gem home. Either ruby may be given as TAG@GEMSET to use a
+project or named gemset instead. Gems already installed in the target are
+skipped.
+
+The gems are installed by the target ruby's gem command in batches of
+--batch gems, 10 by default, with up to --jobs batches, by default the number
+of CPUs, running concurrently. Concurrent batches are installed into their
+own staging dirs and moved into the target gem home once installed, so the
+gem commands never write to the same gem home. The gems of a failing batch
+are retried one by one, after the other batches, so that the gems that can't
+be installed, e.g. those whose native extensions don't compile, are reported.
+The --dry-run option lists the gems to install without installing them.`,
+	Flags: []Flag{
+		{Name: `jobs`, Value: `N`, Usage: "run up to N batches concurrently, the number of CPUs by default"},
+		{Name: `batch`, Value: `N`, Usage: "install N gems per gem command, 10 by default"},
+		{Name: `dry-run`, Usage: "list the gems to install without installing them"},
+	},
+	Run: adminGemsMigrate,
+}
+
+func init() {
+	adminRouter.Handle(adminGemsCmd.Aliases, adminGemsCmd)
+	gemsRouter.Handle(gemsMigrateCmd.Aliases, gemsMigrateCmd)
+}
+
+// Default number of gems installed by a single `gem install` invocation when
+// migrating gems between rubies.
+const migrateBatchSize = 10
+
+type migrateOptions struct {
+	jobs   int  // maximum number of concurrently running batches
+	batch  int  // number of gems installed per batch
+	dryRun bool // list the gems to install without installing them
+	from   string
+	to     string
+}
+
+// gemFailure records a gem that could not be installed and the reason.
+type gemFailure struct {
+	gem    gemSpec
+	reason string
+}
+
+func adminGemsMigrate(ctx *env.Context) error {
+	opts, err := parseMigrateArgs(ctx)
+	if err != nil {
+		return err
+	}
+	failures, err := gemsMigrate(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		// the failed gems are listed in the migration summary
+		return exitStatus(ExitRubyFailed)
+	}
+
+	return nil
+}
+
+// parseMigrateArgs returns the `admin gems migrate` options along with the
+// source and target `TAG[@GEMSET]` names.
+func parseMigrateArgs(ctx *env.Context) (opts migrateOptions, err error) {
+	opts.jobs, opts.batch, opts.dryRun = runtime.NumCPU(), migrateBatchSize, ctx.IsFlagSet(`dry-run`)
+
+	for _, o := range []struct {
+		name string
+		val  *int
+	}{{`jobs`, &opts.jobs}, {`batch`, &opts.batch}} {
+		if !ctx.IsFlagSet(o.name) {
+			continue
+		}
+		v := ctx.Flag(o.name)
+		if *o.val, err = strconv.Atoi(v); err != nil || *o.val < 1 {
+			return opts, fmt.Errorf("[ERROR] invalid `admin gems migrate --%s %s` value.", o.name, v)
+		}
+	}
+
+	args := ctx.CmdArgs()
+	if len(args) != 2 {
+		return opts, errors.New("[ERROR] invalid `admin gems migrate FROM TO` invocation.")
+	}
+	opts.from, opts.to = args[0], args[1]
+
+	return
+}
+
+// Implements the functionality for the user visible command
+//
+//    uru admin gems migrate [--jobs N] [--batch N] [--dry-run] FROM TO
+//
+// which reads the gem specifications installed in the FROM ruby's gem home, or
+// gemset when given as TAG@GEMSET, and installs the same gems and versions into
+// the TO ruby's gem home or gemset. Gems already installed in the target are
+// skipped. The gems are installed in batches using the target ruby's `gem`
+// command, with up to `--jobs` batches running concurrently. If a batch fails
+// its gems are retried individually so that the failing gems, for example
+// those whose native extensions don't compile, can be reported.
+func gemsMigrate(ctx *env.Context, opts migrateOptions) (failures []gemFailure, err error) {
+	fromHash, fromRb, fromDir, err := resolveGemTarget(ctx, opts.from)
+	if err != nil {
+		return
+	}
+	toHash, toRb, installDir, err := resolveGemTarget(ctx, opts.to)
+	if err != nil {
+		return
+	}
+
+	// ask the rubies without a configured gem home where their gems are
+	// installed
+	if fromDir == `` {
+		if fromDir, err = rubyGemDir(ctx, fromHash); err != nil {
+			return
+		}
+	}
+	toDir := installDir
+	if toDir == `` {
+		if toDir, err = rubyGemDir(ctx, toHash); err != nil {
+			return
+		}
+	}
+	if fromDir == toDir && fromHash == toHash {
+		return nil, errors.New("---> unable to migrate gems; FROM and TO are the same")
+	}
+
+	source, err := readGemSpecs(fromDir, rubyLibVersion(fromRb))
+	if err != nil {
+		return
+	}
+
+	// skip gems already installed in the target
+	installed, _ := readGemSpecs(toDir, rubyLibVersion(toRb))
+	var pending []gemSpec
+	for _, g := range source {
+		if len(missingGemSpecs([]gemSpec{{name: g.name, version: g.version}}, installed)) > 0 {
+			pending = append(pending, g)
+		}
+	}
+
+	batches := gemBatches(pending, opts.batch)
+	ctx.Infof("---> migrating %d gems from %s %s to %s %s in %d batches (%d already installed)\n",
+		len(pending), fromRb.Exe, fromRb.ID, toRb.Exe, toRb.ID, len(batches), len(source)-len(pending))
+
+	if opts.dryRun {
+		for _, g := range pending {
+			fmt.Fprintf(ctx.Stdout, "  %s (%s)\n", g.name, g.version)
+		}
+		fmt.Fprintln(ctx.Stdout, "\n---> dry run; nothing installed")
+		return
+	}
+
+	if opts.jobs > 1 && len(batches) > 1 {
+		if failures, err = installBatchesConcurrent(ctx, toHash, installDir, toDir, batches, opts.jobs); err != nil {
+			return
+		}
+	} else {
+		for i, b := range batches {
+			f := installGems(ctx, toHash, installDir, b)
+			ctx.Infof("---> batch %d/%d: %d of %d gems installed\n", i+1, len(batches), len(b)-len(f), len(b))
+			failures = append(failures, f...)
+		}
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\n---> migration summary: %d installed, %d failed\n", len(pending)-len(failures), len(failures))
+	for _, f := range failures {
+		fmt.Fprintf(ctx.Stdout, "  %s (%s): %s\n", f.gem.name, f.gem.version, f.reason)
+	}
+
+	return failures, nil
+}
+
+// gemBatches splits the gems into batches of at most size gems.
+func gemBatches(gems []gemSpec, size int) (batches [][]gemSpec) {
+	for len(gems) > size {
+		batches = append(batches, gems[:size])
+		gems = gems[size:]
+	}
+	if len(gems) > 0 {
+		batches = append(batches, gems)
+	}
+
+	return
+}
+
+// installGems installs a batch of gems with a single `gem install` run by the
+// ruby identified by the tag hash, retrying each gem individually if the batch
+// fails. The failures are returned.
+func installGems(ctx *env.Context, tagHash, installDir string, gems []gemSpec) (failures []gemFailure) {
+	args := []string{`install`, `--no-document`, `--ignore-dependencies`}
+	if installDir != `` {
+		args = append(args, `--install-dir`, installDir)
+	}
+
+	names := make([]string, len(gems))
+	for i, g := range gems {
+		names[i] = fmt.Sprintf("%s:%s", g.name, g.version)
+	}
+	if r := runRubyCaptured(ctx, tagHash, `gem`, append(args, names...)); r.Passed() {
+		return nil
+	} else if len(gems) == 1 {
+		return []gemFailure{{gem: gems[0], reason: gemFailureReason(r)}}
+	}
+
+	for _, g := range gems {
+		failures = append(failures, installGems(ctx, tagHash, installDir, []gemSpec{g})...)
+	}
+
+	return
+}
+
+// installBatchesConcurrent installs up to jobs batches of gems at a time into
+// the target gem dir. Concurrent `gem install` runs writing to the same gem
+// home can corrupt it, so each batch is installed into its own staging dir
+// under toDir and moved into toDir once installed. The executables are
+// installed directly into the target's bin dir. The gems of failed batches are
+// retried after the other batches finish, one batch at a time, so that gems
+// depending on gems from other batches can be installed. The failures are
+// returned.
+func installBatchesConcurrent(ctx *env.Context, tagHash, installDir, toDir string, batches [][]gemSpec, jobs int) (failures []gemFailure, err error) {
+	binDir := filepath.Join(installDir, `bin`)
+	if installDir == `` {
+		if binDir, err = rubyGemPath(ctx, tagHash, `Gem.bindir`, `gem bin dir`); err != nil {
+			return
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var retry [][]gemSpec
+	sem := make(chan struct{}, jobs)
+
+	for i, b := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, b []gemSpec) {
+			defer func() { <-sem; wg.Done() }()
+
+			e := installStagedGems(ctx, tagHash, toDir, binDir, b)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if e != nil {
+				ctx.Infof("---> batch %d/%d: %v; retrying its gems later\n", i+1, len(batches), e)
+				retry = append(retry, b)
+				return
+			}
+			ctx.Infof("---> batch %d/%d: %d of %d gems installed\n", i+1, len(batches), len(b), len(b))
+		}(i, b)
+	}
+	wg.Wait()
+
+	for i, b := range retry {
+		f := installGems(ctx, tagHash, installDir, b)
+		ctx.Infof("---> retried batch %d/%d: %d of %d gems installed\n", i+1, len(retry), len(b)-len(f), len(b))
+		failures = append(failures, f...)
+	}
+
+	return
+}
+
+// installStagedGems installs a batch of gems into a new staging dir under the
+// target gem dir and, if all the gems install, moves them into the target.
+func installStagedGems(ctx *env.Context, tagHash, toDir, binDir string, gems []gemSpec) error {
+	if err := os.MkdirAll(toDir, 0755); err != nil {
+		return fmt.Errorf("unable to create `%s`", toDir)
+	}
+	staging, err := ioutil.TempDir(toDir, `.uru_migrate`)
+	if err != nil {
+		return fmt.Errorf("unable to create a staging dir in `%s`", toDir)
+	}
+	defer os.RemoveAll(staging)
+
+	args := []string{`install`, `--no-document`, `--ignore-dependencies`,
+		`--install-dir`, staging, `--bindir`, binDir}
+	for _, g := range gems {
+		args = append(args, fmt.Sprintf("%s:%s", g.name, g.version))
+	}
+	if r := runRubyCaptured(ctx, tagHash, `gem`, args); !r.Passed() {
+		return errors.New(gemFailureReason(r))
+	}
+
+	return mergeDir(staging, toDir)
+}
+
+// mergeDir moves the contents of the src dir into the dst dir, merging the
+// sub-dirs existing in both and replacing the files existing in both.
+func mergeDir(src, dst string) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("unable to read `%s`", src)
+	}
+
+	for _, e := range entries {
+		s, d := filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())
+		if fi, err := os.Stat(d); err == nil && fi.IsDir() && e.IsDir() {
+			if err = mergeDir(s, d); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = os.Rename(s, d); err != nil {
+			return fmt.Errorf("unable to move `%s` to `%s`", s, d)
+		}
+	}
+
+	return nil
+}
+
+// gemFailureReason summarizes why a `gem install` run failed using the last,
+// and typically most specific, RubyGems error line of its output.
+func gemFailureReason(r *rubyResult) string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+
+	reason := fmt.Sprintf("exit status %d", r.ExitCode)
+	for _, line := range strings.Split(string(r.Output), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, `ERROR:`) {
+			reason = strings.Join(strings.Fields(strings.TrimPrefix(line, `ERROR:`)), ` `)
+		}
+	}
+
+	return reason
+}
+
+// rubyGemDir returns the gem dir reported by the ruby identified by the tag
+// hash, for rubies registered without a gem home.
+func rubyGemDir(ctx *env.Context, tagHash string) (string, error) {
+	return rubyGemPath(ctx, tagHash, `Gem.dir`, `gem dir`)
+}
+
+// rubyGemPath returns the path printed by a RubyGems expression, e.g.
+// `Gem.bindir`, run by the ruby identified by the tag hash.
+func rubyGemPath(ctx *env.Context, tagHash, expr, desc string) (string, error) {
+	r := runRubyCaptured(ctx, tagHash, `ruby`, []string{`-e`, `print ` + expr})
+	if !r.Passed() {
+		return ``, fmt.Errorf("---> unable to determine %s for ruby tagged as `%s`", desc, r.Ruby.TagLabel)
+	}
+
+	return string(bytes.TrimSpace(r.Output)), nil
+}
@@ -0,0 +1,216 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	parse := func(args []string) (migrateOptions, error) {
+		flags, rest, err := gemsMigrateCmd.parseFlags(args)
+		if err != nil {
+			return migrateOptions{}, err
+		}
+		ctx := env.NewContext()
+		ctx.SetFlags(flags)
+		ctx.SetCmdArgs(rest)
+		return parseMigrateArgs(ctx)
+	}
+
+	opts, err := parse([]string{`--jobs`, `3`, `--batch`, `5`, `--dry-run`, `322`, `323@rails7`})
+	if err != nil {
+		t.Fatalf("parseMigrateArgs() returned error: %v", err)
+	}
+	want := migrateOptions{jobs: 3, batch: 5, dryRun: true, from: `322`, to: `323@rails7`}
+	if opts != want {
+		t.Errorf("parseMigrateArgs() not returning correct value\n  want: `%+v`\n  got: `%+v`", want, opts)
+	}
+
+	var invalid = [][]string{
+		{`322`},
+		{`322`, `323`, `331`},
+		{`--batch`, `0`, `322`, `323`},
+		{`--batch`},
+		{`--jobs`, `0`, `322`, `323`},
+		{`--jobs`, `x`, `322`, `323`},
+		{`--dryrun`, `322`, `323`},
+	}
+	for _, v := range invalid {
+		if _, err := parse(v); err == nil {
+			t.Errorf("parseMigrateArgs() not returning error for `%v`", v)
+		}
+	}
+}
+
+func TestGemBatches(t *testing.T) {
+	gems := make([]gemSpec, 7)
+
+	var tests = []struct {
+		Size int
+		Want []int
+	}{
+		{3, []int{3, 3, 1}},
+		{7, []int{7}},
+		{10, []int{7}},
+	}
+
+	for _, v := range tests {
+		batches := gemBatches(gems, v.Size)
+		got := make([]int, len(batches))
+		for i, b := range batches {
+			got[i] = len(b)
+		}
+		if len(got) != len(v.Want) {
+			t.Errorf("gemBatches() not returning correct batches\n  want: `%v`\n  got: `%v`", v.Want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != v.Want[i] {
+				t.Errorf("gemBatches() not returning correct batches\n  want: `%v`\n  got: `%v`", v.Want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestGemFailureReason(t *testing.T) {
+	r := &rubyResult{ExitCode: 1, Output: []byte("ERROR:  Error installing json:\n" +
+		"\tERROR: Failed to build gem native extension.\n\n    current directory: /tmp\n")}
+	want := `Failed to build gem native extension.`
+	if got := gemFailureReason(r); got != want {
+		t.Errorf("gemFailureReason() not returning correct value\n  want: `%v`\n  got: `%v`", want, got)
+	}
+
+	r = &rubyResult{ExitCode: 2}
+	if got := gemFailureReason(r); got != `exit status 2` {
+		t.Errorf("gemFailureReason() not returning correct value\n  want: `exit status 2`\n  got: `%v`", got)
+	}
+}
+
+func TestGemsMigrate(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip("fake ruby scripts require a *nix shell")
+	}
+
+	writeSpecs := func(gemHome string, specs ...string) {
+		os.MkdirAll(filepath.Join(gemHome, `specifications`), 0750)
+		for i, spec := range specs {
+			ioutil.WriteFile(filepath.Join(gemHome, `specifications`, fmt.Sprintf("%d.gemspec", i)),
+				[]byte(spec), 0640)
+		}
+	}
+
+	// the target ruby has no configured gem home, so uru asks the fake ruby
+	// for its gem dir, and the fake gem logs each install
+	fromHome, toDir, bindir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeSpecs(fromHome, jsonGemspec, nokogiriGemspec)
+	writeSpecs(toDir, jsonGemspec)
+	installLog := filepath.Join(bindir, `installs.log`)
+	ioutil.WriteFile(filepath.Join(bindir, `ruby`),
+		[]byte(fmt.Sprintf("#!/bin/sh\nprintf %%s '%s'\n", toDir)), 0750)
+	ioutil.WriteFile(filepath.Join(bindir, `gem`),
+		[]byte(fmt.Sprintf("#!/bin/sh\necho \"$@\" >> '%s'\n", installLog)), 0750)
+
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Stdout = &strings.Builder{}
+	ctx.Registry.Rubies = env.RubyMap{
+		`1264043201`: {TagLabel: `322p53`, ID: `3.2.2-p53`, Exe: `ruby`, Home: t.TempDir(), GemHome: fromHome},
+		`2447330651`: {TagLabel: `323p100`, ID: `3.2.3-p100`, Exe: `ruby`, Home: bindir},
+	}
+
+	failures, err := gemsMigrate(ctx, migrateOptions{jobs: 1, batch: 1, from: `322p53`, to: `323p100`})
+	if err != nil || len(failures) != 0 {
+		t.Fatalf("gemsMigrate() returned error: %v, %v", err, failures)
+	}
+	b, _ := ioutil.ReadFile(installLog)
+	if want := "install --no-document --ignore-dependencies nokogiri:1.15.4\n"; string(b) != want {
+		t.Errorf("gemsMigrate() not installing only the missing gems into the target's gem dir\n  want: `%q`\n  got: `%q`",
+			want, b)
+	}
+}
+
+func TestGemsMigrateConcurrent(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip("fake ruby scripts require a *nix shell")
+	}
+
+	fromHome, toDir, toBin, bindir := t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(fromHome, `specifications`), 0750)
+	for _, name := range []string{`alpha`, `beta`, `broken`} {
+		ioutil.WriteFile(filepath.Join(fromHome, `specifications`, name+`-1.0.gemspec`),
+			[]byte(fmt.Sprintf("Gem::Specification.new do |s|\n  s.name = %q\n  s.version = \"1.0\"\nend\n", name)), 0640)
+	}
+
+	// the fake gem logs each install, fails to install `broken`, and installs
+	// the other gems into the --install-dir dir
+	installLog := filepath.Join(bindir, `installs.log`)
+	ioutil.WriteFile(filepath.Join(bindir, `ruby`), []byte(fmt.Sprintf(`#!/bin/sh
+case "$2" in
+  *bindir*) printf %%s '%s' ;;
+  *) printf %%s '%s' ;;
+esac
+`, toBin, toDir)), 0750)
+	ioutil.WriteFile(filepath.Join(bindir, `gem`), []byte(fmt.Sprintf(`#!/bin/sh
+echo "$@" >> '%s'
+dir=
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --install-dir) dir="$2"; shift ;;
+    broken:*) echo 'ERROR:  Failed to build gem native extension.'; exit 1 ;;
+    *:*) [ -n "$dir" ] && mkdir -p "$dir/gems/${1%%%%:*}" "$dir/specifications" &&
+           echo "s.name = \"${1%%%%:*}\"" > "$dir/specifications/${1%%%%:*}.gemspec" ;;
+  esac
+  shift
+done
+`, installLog)), 0750)
+
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Stdout = &strings.Builder{}
+	ctx.Registry.Rubies = env.RubyMap{
+		`1264043201`: {TagLabel: `322p53`, ID: `3.2.2-p53`, Exe: `ruby`, Home: t.TempDir(), GemHome: fromHome},
+		`2447330651`: {TagLabel: `323p100`, ID: `3.2.3-p100`, Exe: `ruby`, Home: bindir},
+	}
+
+	failures, err := gemsMigrate(ctx, migrateOptions{jobs: 3, batch: 1, from: `322p53`, to: `323p100`})
+	if err != nil {
+		t.Fatalf("gemsMigrate() returned error: %v", err)
+	}
+	if len(failures) != 1 || failures[0].gem.name != `broken` ||
+		failures[0].reason != `Failed to build gem native extension.` {
+		t.Errorf("gemsMigrate() not reporting the failing gem\n  got: `%+v`", failures)
+	}
+
+	// the installed gems are moved from their staging dirs into the target
+	for _, name := range []string{`alpha`, `beta`} {
+		if _, err := os.Stat(filepath.Join(toDir, `gems`, name)); err != nil {
+			t.Errorf("gemsMigrate() not moving `%s` into the target's gem dir", name)
+		}
+	}
+	entries, _ := filepath.Glob(filepath.Join(toDir, `.uru_migrate*`))
+	if len(entries) != 0 {
+		t.Errorf("gemsMigrate() not removing its staging dirs\n  got: `%v`", entries)
+	}
+
+	// the concurrent batches install into staging dirs with the executables in
+	// the target's bin dir, the failed batch is retried in the target
+	b, _ := ioutil.ReadFile(installLog)
+	log := string(b)
+	if want := fmt.Sprintf("--bindir %s alpha:1.0\n", toBin); !strings.Contains(log, want) {
+		t.Errorf("gemsMigrate() not installing batches into staging dirs\n  want: `%s`\n  got: `%s`", want, log)
+	}
+	if want := "install --no-document --ignore-dependencies broken:1.0\n"; !strings.Contains(log, want) {
+		t.Errorf("gemsMigrate() not retrying the failed batch in the target\n  want: `%s`\n  got: `%s`", want, log)
+	}
+}
@@ -6,89 +6,143 @@ package command
 import (
 	"errors"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
+var gemsetRouter *Router = NewRouter(nil)
+
 var adminGemsetCmd *Command = &Command{
 	Name:    "gemset",
 	Aliases: []string{"gemset", "gs"},
-	Usage:   "admin gemset init NAME... | rm",
-	Eg:      "admin gemset init 211@gemset",
+	Usage:   "admin gemset SUBCMD ARGS",
+	Eg:      "admin gemset init 211@gemset 32@rails7",
 	Short:   "administer gemset installations",
-	Run:     adminGemset,
+	Long: `The gemset sub-commands manage gemsets, the isolated gem environments used
+along with a ruby. A project gemset lives in the .gem dir of a project's root
+dir and is named 'gemset', while named gemsets live in uru's home dir and can
+be used from any dir. Use a gemset with a ruby by giving both, e.g.
+'uru 322@gemset' or 'uru 322@rails7'.`,
+	Subcommands: gemsetRouter,
+}
+
+var gemsetInitCmd *Command = &Command{
+	Name:    "init",
+	Aliases: []string{"init"},
+	Usage:   "admin gemset init NAME...",
+	Eg:      "admin gemset init 211@gemset 32@rails7",
+	Short:   "create a project or named gemset",
+	Long: `Creates a gemset for the ruby identified by TAG for each TAG@GEMSET name.
+The reserved 'gemset' name creates a project gemset in the current dir, which
+should be the project's root dir, while any other name creates a named gemset
+in uru's home dir. Each gemset contains a gem home per ruby engine and ruby
+library version:
+
+    PROJECT_ROOT/.gem/ENGINE/RUBY_LIB_VERSION
+    URU_HOME/gemsets/NAME/ENGINE/RUBY_LIB_VERSION
+
+so different rubies can share one gemset name.`,
+	Flags: []Flag{},
+	Run:   adminGemsetInit,
 }
 
+var gemsetLsCmd *Command = &Command{
+	Name:    "ls",
+	Aliases: []string{"ls", "list"},
+	Usage:   "admin gemset ls [--format FORMAT]",
+	Eg:      "admin gemset ls",
+	Short:   "list named and project gemsets",
+	Long: `Lists the named gemsets in uru's home dir and, if present, the current dir's
+project gemset, along with the ruby engines and library versions each
+gemset contains.
+
+The --format option displays the gemsets for scripts: a versioned JSON
+document, tab separated values, or a Go text/template applied to each gemset.`,
+	Flags: []Flag{formatFlag},
+	Run:   adminGemsetList,
+}
+
+var gemsetNameRegex *regexp.Regexp
+
 func init() {
 	adminRouter.Handle(adminGemsetCmd.Aliases, adminGemsetCmd)
+	gemsetRouter.Handle(gemsetInitCmd.Aliases, gemsetInitCmd)
+	gemsetRouter.Handle(gemsetLsCmd.Aliases, gemsetLsCmd)
+
+	var err error
+	gemsetNameRegex, err = regexp.Compile(`\A\w[\w.-]*\z`)
+	if err != nil {
+		panic("unable to compile gemset name regexp")
+	}
 }
 
-func adminGemset(ctx *env.Context) {
+func adminGemsetInit(ctx *env.Context) error {
 	cmdArgs := ctx.CmdArgs()
-	argsLen := len(cmdArgs)
-	if argsLen == 0 {
-		fmt.Println("[ERROR] must specify a gemset operation.")
-		os.Exit(1)
+	if argsLen := len(cmdArgs); argsLen < 1 || argsLen > 19 { // artificial upper limit
+		return errors.New("[ERROR] invalid `admin gemset init NAME...` invocation.")
 	}
 
-	var rubyName, gemsetName string
-	var err error
-
-	switch subCmd := cmdArgs[0]; subCmd {
-	case `init`:
-		if argsLen < 2 || argsLen > 20 { // artificial upper limit
-			fmt.Println("[ERROR] invalid `admin gemset init NAME...` invocation.")
-			os.Exit(1)
-		}
-
-		for _, v := range cmdArgs[1:] {
-			if rubyName, gemsetName, err = parseGemsetName(v); err != nil {
-				fmt.Println("---> invalid `admin gemset init NAME...` invocation.")
-				continue
-			}
-			if err = gemsetInit(ctx, rubyName, gemsetName); err != nil {
-				fmt.Println(err)
-				continue
-			}
+	// keep initializing the remaining gemsets, reporting each failure
+	failed := false
+	for _, v := range cmdArgs {
+		rubyName, gemsetName, err := parseGemsetName(v)
+		if err != nil {
+			fmt.Fprintln(ctx.Stderr, "---> invalid `admin gemset init NAME...` invocation.")
+			failed = true
+			continue
 		}
-	case `rm`:
-		if err := gemsetRemove(ctx); err != nil {
-			fmt.Println("[ERROR] unable to remove the gemset.")
-			log.Printf("[DEBUG] === gemset remove error ===\n%v\n", err)
-			os.Exit(1)
+		if err = gemsetInit(ctx, rubyName, gemsetName); err != nil {
+			fmt.Fprintln(ctx.Stderr, err)
+			failed = true
 		}
-	default:
-		fmt.Printf("[ERROR] I don't understand the `%s` gemset sub-command\n\n", subCmd)
 	}
+	if failed {
+		return exitStatus(ExitError)
+	}
+
+	return nil
 }
 
-// Create a skeleton gemset directory structure in the current directory with
-// the following layout
+func adminGemsetList(ctx *env.Context) error {
+	if len(ctx.CmdArgs()) != 0 {
+		return errors.New("[ERROR] invalid `admin gemset ls` invocation.")
+	}
+
+	return gemsetList(ctx)
+}
+
+// Create a skeleton gemset directory structure with the following layout
 //
-//    <PROJECT_ROOT>/.gem/$ENGINE/$RUBY_LIB_VERSION
+//    <PROJECT_ROOT>/.gem/$ENGINE/$RUBY_LIB_VERSION     (project gemset)
+//    $URU_HOME/gemsets/<NAME>/$ENGINE/$RUBY_LIB_VERSION (named gemset)
 //
 // $ENGINE is the name of the main ruby executable: ruby, jruby, or rbx
 // $RUBY_LIB_VERSION is uru's interpretation of ruby's library version
 // based upon the RUBY_DESCRIPTION string.
 //
-// While there is a single gemset per project directory, multiple gem
-// environments can mutually coexist due to the above gemset directory
-// structure. Essentially, a project's gemset is parameterized by both
-// $ENGINE and $RUBY_LIB_VERSION.
+// While there is a single project gemset per project directory and a single
+// named gemset per name, multiple gem environments can mutually coexist due to
+// the above gemset directory structure. Essentially, a gemset is parameterized
+// by both $ENGINE and $RUBY_LIB_VERSION.
 //
-// Implements the functionality for the user visible command
+// Implements the functionality for the user visible commands
 //
 //    uru admin gemset init <RUBY_NAME>@gemset
+//    uru admin gemset init <RUBY_NAME>@<NAME>
 //
-// that should be invoked in the root directory of the project in order
-// for gemsets to function correctly.
+// where the reserved `gemset` name creates a project gemset and should be
+// invoked in the root directory of the project in order for gemsets to
+// function correctly. Any other name creates a named gemset usable from any
+// directory.
 func gemsetInit(ctx *env.Context, ruby, gemset string) (err error) {
-	if gemset != `gemset` {
-		return errors.New("---> unable to initialize gemset. Only project gemsets supported")
+	if !gemsetNameRegex.MatchString(gemset) {
+		return fmt.Errorf("---> unable to initialize gemset. Invalid gemset name `%s`", gemset)
 	}
 
 	dir, err := gemsetDirName(ctx, ruby, gemset)
@@ -96,10 +150,14 @@ func gemsetInit(ctx *env.Context, ruby, gemset string) (err error) {
 		return
 	}
 
-	fmt.Printf("---> initializing project gemset for ruby matching `%s` label\n", ruby)
+	if gemset == `gemset` {
+		ctx.Infof("---> initializing project gemset for ruby matching `%s` label\n", ruby)
+	} else {
+		ctx.Infof("---> initializing `%s` gemset for ruby matching `%s` label\n", gemset, ruby)
+	}
 
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		log.Printf("[DEBUG] creating gemset dir `%s`\n", dir)
+		log.Debugf("creating gemset dir `%s`\n", dir)
 		os.MkdirAll(dir, os.ModeDir|0750)
 	}
 
@@ -117,25 +175,154 @@ func gemsetDirName(ctx *env.Context, ruby, gemset string) (dirName string, err e
 		return ``, errors.New(fmt.Sprintf("---> unable to find ruby specific to `%s`; try again", ruby))
 	}
 
-	var engine, rbLibVersion string
 	for _, t := range tags {
-		engine = t.Exe
-		rbLibVersion = strings.Split(t.ID, `-`)[0]
-	}
-	switch {
-	case rbLibVersion >= `2.1.0`:
-		rbLibVersion = fmt.Sprintf("%s.0", env.RbMajMinRegex.FindStringSubmatch(rbLibVersion)[0])
+		dirName, err = gemsetDir(ctx, t, gemset)
 	}
 
+	return
+}
+
+// Return the directory path name of the gemset for the given ruby. The
+// reserved `gemset` name refers to the project gemset in the current directory
+// while all other names refer to named gemsets stored under uru's home dir.
+func gemsetDir(ctx *env.Context, rb env.Ruby, gemset string) (dirName string, err error) {
 	var rootDir string
 	if gemset == `gemset` {
 		rootDir, err = os.Getwd()
 		if err != nil {
 			return ``, errors.New("---> unable to determine current working dir")
 		}
+		rootDir = filepath.Join(rootDir, `.gem`)
+	} else {
+		rootDir = filepath.Join(ctx.Home(), `gemsets`, gemset)
+	}
+
+	dirName = filepath.Join(rootDir, rb.Exe, rubyLibVersion(rb))
+
+	return
+}
+
+// Return uru's interpretation of a ruby's library version, e.g. `3.2.0` for
+// ruby 3.2.2, which is also the ABI version of the ruby's native extensions.
+func rubyLibVersion(rb env.Ruby) string {
+	rbLibVersion := strings.Split(rb.ID, `-`)[0]
+	switch {
+	case rbLibVersion >= `2.1.0`:
+		rbLibVersion = fmt.Sprintf("%s.0", env.RbMajMinRegex.FindStringSubmatch(rbLibVersion)[0])
+	}
+
+	return rbLibVersion
+}
+
+// Return the name and GEM_HOME dir of the gemset activated for the given ruby
+// by inspecting the GEM_HOME env var. The name is `gemset` for a project
+// gemset and empty when no gemset is active.
+func activeGemset(ctx *env.Context, rb env.Ruby) (name, dir string) {
+	dir = os.Getenv(`GEM_HOME`)
+	if dir == `` || dir == rb.GemHome {
+		return ``, ``
+	}
+
+	// both gemset layouts end with $ENGINE/$RUBY_LIB_VERSION
+	want, err := gemsetDir(ctx, rb, `gemset`)
+	if err != nil {
+		return ``, ``
+	}
+	suffix := filepath.Join(filepath.Base(filepath.Dir(want)), filepath.Base(want))
+	if filepath.Join(filepath.Base(filepath.Dir(dir)), filepath.Base(dir)) != suffix {
+		return ``, ``
+	}
+
+	root := filepath.Dir(filepath.Dir(dir))
+	switch {
+	case filepath.Base(root) == `.gem`:
+		return `gemset`, dir
+	case filepath.Dir(root) == filepath.Join(ctx.Home(), `gemsets`):
+		return filepath.Base(root), dir
+	}
+
+	return ``, ``
+}
+
+// gemsetRecord is the machine readable form of a gemset displayed by
+// `admin gemset ls --format`. Its fields are part of uru's documented output
+// schema.
+type gemsetRecord struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"` // `named` or `project`
+	Dir    string   `json:"dir"`
+	Rubies []string `json:"rubies"`
+}
+
+func newGemsetRecord(name, typ, dir string) gemsetRecord {
+	r := gemsetRecord{Name: name, Type: typ, Dir: dir, Rubies: gemsetRubies(dir)}
+	if r.Rubies == nil {
+		r.Rubies = []string{}
+	}
+	return r
+}
+
+// Implements the functionality for the user visible command
+//
+//    uru admin gemset ls [--format FORMAT]
+//
+// that lists the named gemsets and, if present, the current directory's
+// project gemset along with the engine and library versions each supports.
+func gemsetList(ctx *env.Context) (err error) {
+	format, err := parseOutputFormat(ctx)
+	if err != nil {
+		return
+	}
+	gemsetsDir := filepath.Join(ctx.Home(), `gemsets`)
+
+	entries, err := ioutil.ReadDir(gemsetsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("---> unable to read gemsets dir `%s`", gemsetsDir)
+	}
+	gemsets := []gemsetRecord{}
+	for _, e := range entries {
+		if e.IsDir() {
+			gemsets = append(gemsets, newGemsetRecord(e.Name(), `named`, filepath.Join(gemsetsDir, e.Name())))
+		}
 	}
 
-	dirName = filepath.Join(rootDir, `.gem`, engine, rbLibVersion)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return errors.New("---> unable to determine current working dir")
+	}
+	project := newGemsetRecord(`gemset`, `project`, filepath.Join(cwd, `.gem`))
+
+	if !format.isDefault() {
+		if len(project.Rubies) > 0 {
+			gemsets = append(gemsets, project)
+		}
+		return format.write(ctx, `gemsets`, gemsets)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "---> named gemsets in `%s`\n\n", gemsetsDir)
+	for _, g := range gemsets {
+		fmt.Fprintf(ctx.Stdout, "  %-12s  %s\n", g.Name, strings.Join(g.Rubies, `, `))
+	}
+	if len(gemsets) == 0 {
+		fmt.Fprintln(ctx.Stdout, "  none")
+	}
+	if len(project.Rubies) > 0 {
+		fmt.Fprintf(ctx.Stdout, "\n---> project gemset in `%s`\n\n", cwd)
+		fmt.Fprintf(ctx.Stdout, "  %-12s  %s\n", project.Name, strings.Join(project.Rubies, `, `))
+	}
+
+	return nil
+}
+
+// Return the `$ENGINE $RUBY_LIB_VERSION` pairs of a gemset root directory.
+func gemsetRubies(rootDir string) (rubies []string) {
+	dirs, _ := filepath.Glob(filepath.Join(rootDir, `*`, `*`))
+	for _, d := range dirs {
+		if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+			continue
+		}
+		rubies = append(rubies, fmt.Sprintf("%s %s", filepath.Base(filepath.Dir(d)), filepath.Base(d)))
+	}
 
 	return
 }
@@ -0,0 +1,442 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var gemsetInfoCmd *Command = &Command{
+	Name:    "info",
+	Aliases: []string{"info"},
+	Usage:   "admin gemset info [--export FILE] [--check LOCKFILE] [--format FORMAT] [NAME]",
+	Eg:      "admin gemset info --check Gemfile.lock 32@rails7",
+	Short:   "list the gems installed in a gemset",
+	Long: `Lists the gems installed in a gemset, or in a ruby's default gem home when
+given only a TAG, by reading their gemspec files without running ruby. Gems
+whose native extensions weren't built for the ruby's ABI version are flagged.
+Without a target, the active gemset, or the active ruby's gem home, is used.
+
+The --export option writes a Gemfile.lock style snapshot of the gems to FILE,
+or to stdout if FILE is '-'. The --check option lists the gems required by a
+Gemfile.lock that are missing from the gemset. The --format option displays
+the gems for scripts: a versioned JSON document, tab separated values, or a
+Go text/template applied to each gem.`,
+	Flags: []Flag{
+		{Name: `export`, Value: `FILE`, Usage: "write a Gemfile.lock style snapshot to FILE, '-' for stdout"},
+		{Name: `check`, Value: `LOCKFILE`, Usage: "list the gems of LOCKFILE missing from the gemset"},
+		formatFlag,
+	},
+	Run: adminGemsetInfo,
+}
+
+var gemStubRegex, gemAttrRegex, lockSpecRegex *regexp.Regexp
+
+func init() {
+	gemsetRouter.Handle(gemsetInfoCmd.Aliases, gemsetInfoCmd)
+
+	var err error
+	gemStubRegex, err = regexp.Compile(`(?m)^# stub: (\S+) (\S+) (\S+) .*\n(?:# stub: (.+))?`)
+	if err != nil {
+		panic("unable to compile gemspec stub regexp")
+	}
+
+	gemAttrRegex, err = regexp.Compile(`(?m)^\s*s\.(name|version|platform|extensions) = (.+)$`)
+	if err != nil {
+		panic("unable to compile gemspec attribute regexp")
+	}
+
+	lockSpecRegex, err = regexp.Compile(`\A    (\S+) \(([^)]+)\)\z`)
+	if err != nil {
+		panic("unable to compile Gemfile.lock spec regexp")
+	}
+}
+
+// gemSpec summarizes an installed gem as described by the gemspec file in a
+// gem home's `specifications` dir.
+type gemSpec struct {
+	name       string
+	version    string
+	platform   string // `ruby` for pure ruby gems
+	extensions bool   // gem has native extensions built at install time
+	size       int64  // disk usage of the installed gem and its extensions
+	abiNote    string // non-empty if native extensions don't match the ruby ABI
+}
+
+// gemRecord is the machine readable form of an installed gem displayed by
+// `admin gemset info --format`. Its fields are part of uru's documented output
+// schema.
+type gemRecord struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Platform   string `json:"platform"`
+	Extensions bool   `json:"extensions"`
+	Size       int64  `json:"size"`
+	ABINote    string `json:"abi_note"`
+}
+
+func newGemRecord(g gemSpec) gemRecord {
+	return gemRecord{
+		Name:       g.name,
+		Version:    g.version,
+		Platform:   g.platform,
+		Extensions: g.extensions,
+		Size:       g.size,
+		ABINote:    g.abiNote,
+	}
+}
+
+// fullName returns the gem's name as used for its install dirs, e.g.
+// `nokogiri-1.15.4-x86_64-linux`.
+func (g gemSpec) fullName() string {
+	return fmt.Sprintf("%s-%s", g.name, g.lockVersion())
+}
+
+// lockVersion returns the gem's version as written in a Gemfile.lock, e.g.
+// `1.15.4-x86_64-linux`.
+func (g gemSpec) lockVersion() string {
+	if g.platform == `` || g.platform == `ruby` {
+		return g.version
+	}
+	return fmt.Sprintf("%s-%s", g.version, g.platform)
+}
+
+func adminGemsetInfo(ctx *env.Context) error {
+	return gemsetInfo(ctx)
+}
+
+// Implements the functionality for the user visible command
+//
+//    uru admin gemset info [--export FILE] [--check LOCKFILE] [--format FORMAT] [TAG[@GEMSET]]
+//
+// which lists the gems installed in a gemset, or a ruby's default gem home, by
+// reading the gemspec files directly rather than activating the gemset. Gems
+// whose native extensions were not built for the ruby's ABI version are
+// flagged. Without a target, the active gemset or the active ruby's gem home is
+// used. The `--export` option writes a Gemfile.lock style snapshot of the gems
+// to FILE, or stdout if FILE is `-`, and `--check` lists the gems required by a
+// Gemfile.lock that are missing from the gemset. The `--format` option displays
+// the gems in a machine readable format instead.
+func gemsetInfo(ctx *env.Context) (err error) {
+	args := ctx.CmdArgs()
+	if len(args) > 1 {
+		return errors.New("[ERROR] invalid `admin gemset info [TAG@GEMSET]` invocation.")
+	}
+	exportFile, lockFile := ctx.Flag(`export`), ctx.Flag(`check`)
+	target := ``
+	if len(args) == 1 {
+		target = args[0]
+	}
+	format, err := parseOutputFormat(ctx)
+	if err != nil {
+		return
+	}
+	if !format.isDefault() && (exportFile != `` || lockFile != ``) {
+		return errors.New("[ERROR] `--format` can't be combined with `--export` or `--check`.")
+	}
+
+	rb, dir, err := gemsetInfoTarget(ctx, target)
+	if err != nil {
+		return
+	}
+	specs, err := readGemSpecs(dir, rubyLibVersion(rb))
+	if err != nil {
+		return
+	}
+
+	if !format.isDefault() {
+		gems := make([]gemRecord, 0, len(specs))
+		for _, g := range specs {
+			gems = append(gems, newGemRecord(g))
+		}
+		return format.write(ctx, `gems`, gems)
+	}
+
+	if exportFile != `-` {
+		printGemSpecs(ctx, dir, rb, specs)
+	}
+
+	if exportFile != `` {
+		if err = exportGemSpecs(ctx, exportFile, specs); err != nil {
+			return
+		}
+	}
+
+	if lockFile != `` {
+		f, e := os.Open(lockFile)
+		if e != nil {
+			return fmt.Errorf("---> unable to open `%s`", lockFile)
+		}
+		defer f.Close()
+
+		required, e := parseLockfileSpecs(f)
+		if e != nil {
+			return fmt.Errorf("---> unable to read `%s`", lockFile)
+		}
+		missing := missingGemSpecs(required, specs)
+
+		fmt.Fprintf(ctx.Stdout, "\n---> checking `%s`: %d of %d gems missing\n", lockFile, len(missing), len(required))
+		for _, m := range missing {
+			fmt.Fprintf(ctx.Stdout, "  %s (%s)\n", m.name, m.lockVersion())
+		}
+		if len(missing) > 0 {
+			// the missing gems have been listed
+			return exitStatus(ExitError)
+		}
+	}
+
+	return
+}
+
+// gemsetInfoTarget returns the ruby and gem home dir identified by a
+// `TAG[@GEMSET]` target, or by the active ruby and gemset if target is empty.
+func gemsetInfoTarget(ctx *env.Context, target string) (rb env.Ruby, dir string, err error) {
+	if target == `` {
+		tagHash, info, e := env.CurrentRubyInfo(ctx)
+		if e != nil || tagHash == `` {
+			return rb, ``, errors.New("---> no active ruby; specify a TAG@GEMSET target")
+		}
+		if r, ok := ctx.Registry.Rubies[tagHash]; ok {
+			info = r
+		}
+		if _, dir = activeGemset(ctx, info); dir == `` {
+			dir = info.GemHome
+		}
+		rb = info
+	} else if _, rb, dir, err = resolveGemTarget(ctx, target); err != nil {
+		return
+	}
+
+	if dir == `` {
+		return rb, ``, fmt.Errorf("---> no gem home registered for %s %s", rb.Exe, rb.ID)
+	}
+	if fi, e := os.Stat(dir); e != nil || !fi.IsDir() {
+		return rb, ``, fmt.Errorf("---> no gemset found at `%s`", dir)
+	}
+
+	return
+}
+
+// resolveGemTarget returns the tag hash, ruby and gem home dir identified by a
+// `TAG[@GEMSET]` target. The dir is the ruby's registered gem home, possibly
+// empty, when the target doesn't name a gemset.
+func resolveGemTarget(ctx *env.Context, target string) (tagHash string, rb env.Ruby, dir string, err error) {
+	ruby, gemset, _ := parseGemsetName(target)
+	tags, e := env.TagLabelToTag(ctx, ruby)
+	if e != nil || len(tags) != 1 {
+		return ``, rb, ``, fmt.Errorf("---> unable to find ruby specific to `%s`; try again", ruby)
+	}
+	for t, ri := range tags {
+		tagHash, rb = t, ri
+	}
+
+	if gemset == `` {
+		dir = rb.GemHome
+	} else {
+		dir, err = gemsetDir(ctx, rb, gemset)
+	}
+
+	return
+}
+
+// readGemSpecs returns the gems installed in a gem home, sorted by name and
+// version. Native extensions are checked against the given ABI version.
+func readGemSpecs(gemHome, abi string) (specs []gemSpec, err error) {
+	files, err := filepath.Glob(filepath.Join(gemHome, `specifications`, `*.gemspec`))
+	if err != nil {
+		return nil, fmt.Errorf("---> unable to read gemspecs in `%s`", gemHome)
+	}
+
+	for _, f := range files {
+		b, e := ioutil.ReadFile(f)
+		if e != nil {
+			return nil, fmt.Errorf("---> unable to read gemspec `%s`", f)
+		}
+		g := parseGemSpec(string(b))
+		if g.name == `` || g.version == `` {
+			continue
+		}
+
+		g.size = dirSize(filepath.Join(gemHome, `gems`, g.fullName()))
+		if g.extensions {
+			built, _ := filepath.Glob(filepath.Join(gemHome, `extensions`, `*`, `*`, g.fullName()))
+			abis := []string{}
+			ok := false
+			for _, d := range built {
+				g.size += dirSize(d)
+				a := filepath.Base(filepath.Dir(d))
+				abis = append(abis, a)
+				if a == abi || strings.HasPrefix(a, abi+`-`) {
+					ok = true
+				}
+			}
+			switch {
+			case ok:
+			case len(abis) == 0:
+				g.abiNote = fmt.Sprintf("native ext not built for ABI %s", abi)
+			default:
+				g.abiNote = fmt.Sprintf("native ext built for ABI %s, not %s", strings.Join(abis, `, `), abi)
+			}
+		}
+
+		specs = append(specs, g)
+	}
+
+	sort.Slice(specs, func(i, j int) bool {
+		if specs[i].name != specs[j].name {
+			return specs[i].name < specs[j].name
+		}
+		return compareVersions(specs[i].version, specs[j].version) < 0
+	})
+
+	return
+}
+
+// parseGemSpec extracts the gem name, version, platform and native extension
+// usage from the contents of an installed gemspec file. The `# stub:` header
+// written by RubyGems is preferred, falling back to the spec's attributes.
+func parseGemSpec(content string) (g gemSpec) {
+	if m := gemStubRegex.FindStringSubmatch(content); m != nil {
+		g.name, g.version, g.platform = m[1], m[2], m[3]
+		g.extensions = strings.TrimSpace(m[4]) != ``
+		return
+	}
+
+	unquote := func(v string) string {
+		v = strings.TrimSuffix(strings.TrimSpace(v), `.freeze`)
+		return strings.Trim(v, `"'`)
+	}
+	for _, m := range gemAttrRegex.FindAllStringSubmatch(content, -1) {
+		switch m[1] {
+		case `name`:
+			g.name = unquote(m[2])
+		case `version`:
+			g.version = unquote(m[2])
+		case `platform`:
+			g.platform = unquote(m[2])
+		case `extensions`:
+			g.extensions = strings.Contains(m[2], `"`) || strings.Contains(m[2], `'`)
+		}
+	}
+	if g.platform == `` {
+		g.platform = `ruby`
+	}
+
+	return
+}
+
+// printGemSpecs displays the gems installed in a gem home.
+func printGemSpecs(ctx *env.Context, dir string, rb env.Ruby, specs []gemSpec) {
+	fmt.Fprintf(ctx.Stdout, "---> gemset `%s` (%s %s)\n\n", dir, rb.Exe, rb.ID)
+
+	var total int64
+	for _, g := range specs {
+		total += g.size
+		line := fmt.Sprintf("  %-24s  %-22s  %10s  %s", g.name, g.lockVersion(), formatSize(g.size), g.abiNote)
+		fmt.Fprintln(ctx.Stdout, strings.TrimRight(line, ` `))
+	}
+	fmt.Fprintf(ctx.Stdout, "\n  %d gems, %s\n", len(specs), formatSize(total))
+}
+
+// exportGemSpecs writes a Gemfile.lock style snapshot of the gems to a file, or
+// to stdout if the file name is `-`.
+func exportGemSpecs(ctx *env.Context, fileName string, specs []gemSpec) (err error) {
+	var w io.Writer = ctx.Stdout
+	if fileName != `-` {
+		f, e := os.Create(fileName)
+		if e != nil {
+			return fmt.Errorf("---> unable to create `%s`", fileName)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err = writeGemLockfile(w, specs); err != nil {
+		return fmt.Errorf("---> unable to write `%s`", fileName)
+	}
+	if fileName != `-` {
+		fmt.Fprintf(ctx.Stdout, "\n---> exported %d gems to `%s`\n", len(specs), fileName)
+	}
+
+	return
+}
+
+// writeGemLockfile writes the GEM and PLATFORMS sections of a Gemfile.lock
+// listing the gems.
+func writeGemLockfile(w io.Writer, specs []gemSpec) error {
+	bw := bufio.NewWriter(w)
+
+	platforms := []string{}
+	fmt.Fprint(bw, "GEM\n  remote: https://rubygems.org/\n  specs:\n")
+	for _, g := range specs {
+		fmt.Fprintf(bw, "    %s (%s)\n", g.name, g.lockVersion())
+		p := g.platform
+		if p == `` {
+			p = `ruby`
+		}
+		if !containsString(platforms, p) {
+			platforms = append(platforms, p)
+		}
+	}
+	sort.Strings(platforms)
+
+	fmt.Fprint(bw, "\nPLATFORMS\n")
+	for _, p := range platforms {
+		fmt.Fprintf(bw, "  %s\n", p)
+	}
+
+	return bw.Flush()
+}
+
+// parseLockfileSpecs returns the gems listed in the GEM sections of a
+// Gemfile.lock. Dependencies of the listed gems are ignored.
+func parseLockfileSpecs(r io.Reader) (specs []gemSpec, err error) {
+	inGem := false
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if line != `` && !strings.HasPrefix(line, ` `) {
+			inGem = line == `GEM`
+			continue
+		}
+		if !inGem {
+			continue
+		}
+		if m := lockSpecRegex.FindStringSubmatch(line); m != nil {
+			specs = append(specs, gemSpec{name: m[1], version: m[2]})
+		}
+	}
+
+	return specs, s.Err()
+}
+
+// missingGemSpecs returns the required gems not found in the installed gems.
+// A required version without a platform suffix matches any installed platform.
+func missingGemSpecs(required, installed []gemSpec) (missing []gemSpec) {
+	for _, r := range required {
+		found := false
+		for _, g := range installed {
+			if g.name == r.name && (g.lockVersion() == r.version || g.version == r.version) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, r)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,181 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var jsonGemspec = `# -*- encoding: utf-8 -*-
+# stub: json 2.6.3 ruby lib
+# stub: ext/json/ext/generator/extconf.rb
+
+Gem::Specification.new do |s|
+  s.name = "json".freeze
+  s.version = "2.6.3"
+end
+`
+
+var nokogiriGemspec = `# -*- encoding: utf-8 -*-
+Gem::Specification.new do |s|
+  s.name = "nokogiri".freeze
+  s.version = "1.15.4"
+  s.platform = "x86_64-linux".freeze
+  s.extensions = []
+end
+`
+
+func TestParseGemSpec(t *testing.T) {
+	var tests = []struct {
+		Content string
+		Want    gemSpec
+	}{
+		{jsonGemspec, gemSpec{name: `json`, version: `2.6.3`, platform: `ruby`, extensions: true}},
+		{nokogiriGemspec, gemSpec{name: `nokogiri`, version: `1.15.4`, platform: `x86_64-linux`}},
+	}
+
+	for _, v := range tests {
+		if got := parseGemSpec(v.Content); got != v.Want {
+			t.Errorf("parseGemSpec() not returning correct value\n  want: `%+v`\n  got: `%+v`",
+				v.Want, got)
+		}
+	}
+}
+
+func TestReadGemSpecs(t *testing.T) {
+	gemHome, err := ioutil.TempDir(``, `uru-gemhome`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gemHome)
+
+	os.MkdirAll(filepath.Join(gemHome, `specifications`), 0750)
+	os.MkdirAll(filepath.Join(gemHome, `extensions`, `x86_64-linux`, `3.1.0`, `json-2.6.3`), 0750)
+	ioutil.WriteFile(filepath.Join(gemHome, `specifications`, `json-2.6.3.gemspec`), []byte(jsonGemspec), 0640)
+	ioutil.WriteFile(filepath.Join(gemHome, `specifications`, `nokogiri-1.15.4-x86_64-linux.gemspec`),
+		[]byte(nokogiriGemspec), 0640)
+
+	specs, err := readGemSpecs(gemHome, `3.2.0`)
+	if err != nil {
+		t.Fatalf("readGemSpecs() returned error: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("readGemSpecs() not returning correct number of gems\n  want: `2`\n  got: `%d`", len(specs))
+	}
+	if !strings.Contains(specs[0].abiNote, `3.1.0`) {
+		t.Errorf("readGemSpecs() not flagging native ext built for a different ABI\n  got: `%s`",
+			specs[0].abiNote)
+	}
+	if specs[1].abiNote != `` {
+		t.Errorf("readGemSpecs() flagging gem without native ext\n  got: `%s`", specs[1].abiNote)
+	}
+
+	if specs, _ = readGemSpecs(gemHome, `3.1.0`); specs[0].abiNote != `` {
+		t.Errorf("readGemSpecs() flagging native ext built for the ruby ABI\n  got: `%s`", specs[0].abiNote)
+	}
+}
+
+func TestGemLockfileRoundTrip(t *testing.T) {
+	installed := []gemSpec{
+		{name: `json`, version: `2.6.3`, platform: `ruby`},
+		{name: `nokogiri`, version: `1.15.4`, platform: `x86_64-linux`},
+	}
+
+	var buf bytes.Buffer
+	if err := writeGemLockfile(&buf, installed); err != nil {
+		t.Fatalf("writeGemLockfile() returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "    nokogiri (1.15.4-x86_64-linux)\n") {
+		t.Errorf("writeGemLockfile() not writing platform specific gem\n  got: `%s`", buf.String())
+	}
+
+	specs, err := parseLockfileSpecs(&buf)
+	if err != nil {
+		t.Fatalf("parseLockfileSpecs() returned error: %v", err)
+	}
+	if missing := missingGemSpecs(specs, installed); len(missing) != 0 {
+		t.Errorf("missingGemSpecs() not returning correct value\n  want: `[]`\n  got: `%v`", missing)
+	}
+}
+
+func TestMissingGemSpecs(t *testing.T) {
+	lockfile := `GIT
+  remote: https://github.com/example/gitgem.git
+  specs:
+    gitgem (0.1.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    json (2.6.3)
+    nokogiri (1.15.4)
+      racc (~> 1.4)
+    racc (1.7.1)
+
+PLATFORMS
+  ruby
+`
+	installed := []gemSpec{
+		{name: `json`, version: `2.6.3`, platform: `ruby`},
+		{name: `nokogiri`, version: `1.15.4`, platform: `x86_64-linux`},
+	}
+
+	specs, err := parseLockfileSpecs(strings.NewReader(lockfile))
+	if err != nil {
+		t.Fatalf("parseLockfileSpecs() returned error: %v", err)
+	}
+
+	want := []gemSpec{{name: `racc`, version: `1.7.1`}}
+	if got := missingGemSpecs(specs, installed); !reflect.DeepEqual(got, want) {
+		t.Errorf("missingGemSpecs() not returning correct value\n  want: `%v`\n  got: `%v`", want, got)
+	}
+}
+
+func TestGemsetInfoJSON(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Registry.Rubies = env.RubyMap{
+		`1264043201`: {TagLabel: `322p53`, ID: `3.2.2-p53`, Exe: `ruby`},
+	}
+	gemHome := filepath.Join(ctx.Home(), `gemsets`, `rails7`, `ruby`, `3.2.0`)
+	os.MkdirAll(filepath.Join(gemHome, `specifications`), 0750)
+	ioutil.WriteFile(filepath.Join(gemHome, `specifications`, `nokogiri-1.15.4-x86_64-linux.gemspec`),
+		[]byte(nokogiriGemspec), 0640)
+
+	var out bytes.Buffer
+	ctx.Stdout = &out
+	ctx.Options.JSON = true
+	ctx.SetCmdAndArgs(`admin`, []string{`gemset`, `info`, `322p53@rails7`})
+	if err := CmdRouter.Dispatch(ctx, `admin`); err != nil {
+		t.Fatalf("admin gemset info with --json returned error: %v", err)
+	}
+
+	var doc struct {
+		SchemaVersion int         `json:"schema_version"`
+		Kind          string      `json:"kind"`
+		Items         []gemRecord `json:"items"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("admin gemset info with --json not writing valid JSON: %v\n%s", err, out.String())
+	}
+	want := []gemRecord{{Name: `nokogiri`, Version: `1.15.4`, Platform: `x86_64-linux`}}
+	if doc.SchemaVersion != OutputSchemaVersion || doc.Kind != `gems` || !reflect.DeepEqual(doc.Items, want) {
+		t.Errorf("admin gemset info with --json not returning correct document\n  want: `%+v`\n  got: `%+v`",
+			want, doc)
+	}
+
+	ctx.SetCmdAndArgs(`admin`, []string{`gemset`, `info`, `--check`, `Gemfile.lock`, `322p53@rails7`})
+	if err := CmdRouter.Dispatch(ctx, `admin`); err == nil {
+		t.Error("admin gemset info not rejecting `--check` with the JSON format")
+	}
+}
@@ -4,36 +4,227 @@
 package command
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
-// Implements the functionality for the incredibly dangerous user command
+var gemsetRmCmd *Command = &Command{
+	Name:    "rm",
+	Aliases: []string{"rm"},
+	Usage:   "admin gemset rm [--dry-run] [NAME]",
+	Eg:      "admin gemset rm --dry-run 32@rails7",
+	Short:   "remove a gemset",
+	Long: `Removes a gemset after listing its gem homes and their sizes and asking for
+confirmation. Without a target, the current dir's entire project gemset is
+removed, so run it from the project's root dir. Given TAG@GEMSET, only the
+target ruby's gem home of the project or named gemset is removed.
+
+The gemset must follow the uru gemset layout, so uru never removes dirs it
+didn't create. The --dry-run option lists the gem homes without removing
+anything.`,
+	Flags: []Flag{
+		{Name: `dry-run`, Usage: "list the gem homes without removing them"},
+	},
+	Run: adminGemsetRemove,
+}
+
+var gemsetVersionRegex *regexp.Regexp
+
+func init() {
+	gemsetRouter.Handle(gemsetRmCmd.Aliases, gemsetRmCmd)
+
+	var err error
+	gemsetVersionRegex, err = regexp.Compile(`\A\d+\.\d+\.\d+\z`)
+	if err != nil {
+		panic("unable to compile gemset version dir regexp")
+	}
+}
+
+func adminGemsetRemove(ctx *env.Context) error {
+	err := gemsetRemove(ctx)
+	if _, ok := err.(*os.PathError); ok {
+		log.Warn("gemset remove failed", "err", err)
+		return errors.New("[ERROR] unable to remove the gemset.")
+	}
+
+	return err
+}
+
+// gemsetSubtree is a single $ENGINE/$RUBY_LIB_VERSION gem environment of a
+// gemset directory tree.
+type gemsetSubtree struct {
+	engine  string
+	version string
+	dir     string
+	size    int64
+}
+
+// Implements the functionality for the dangerous user command
+//
+//    uru admin gemset rm [--dry-run] [TAG@GEMSET]
 //
-//    uru admin gemset rm
+// which, without a target, deletes the entire `.gem` directory tree in the
+// current directory. As such, the user visible command should be run from the
+// root directory of a project containing a gemset. Given a target such as
+// `32@gemset` or `32@rails7`, only the target ruby's subtree of the project or
+// named gemset is deleted.
 //
-// which deletes the entire `.gem` directory tree in the current directory. As
-// such, the user visible command should be run from the root directory of a
-// project containing a gemset.
+// The gemset tree must follow the uru gemset layout; the subtrees to be deleted
+// and their sizes are listed before asking for confirmation. The `--dry-run`
+// option lists the subtrees without deleting anything.
 func gemsetRemove(ctx *env.Context) (err error) {
-	var rv, rootDir string
-	rv, err = env.UIYesConfirm("\nDelete the current project's entire gemset?")
+	args := ctx.CmdArgs()
+	if len(args) > 1 {
+		return errors.New("[ERROR] invalid `admin gemset rm [--dry-run] [TAG@GEMSET]` invocation.")
+	}
+	dryRun := ctx.IsFlagSet(`dry-run`)
+	target := ``
+	if len(args) == 1 {
+		target = args[0]
+	}
+
+	var rootDir string
+	var subtrees []gemsetSubtree
+	if target == `` {
+		cwd, e := os.Getwd()
+		if e != nil {
+			return errors.New("---> unable to determine current working dir")
+		}
+		rootDir = filepath.Join(cwd, `.gem`)
+		if subtrees, err = gemsetSubtrees(rootDir); err != nil {
+			return
+		}
+	} else {
+		ruby, gemset, _ := parseGemsetName(target)
+		if gemset == `` || !gemsetNameRegex.MatchString(gemset) {
+			return fmt.Errorf("[ERROR] invalid `admin gemset rm %s` target; use TAG@GEMSET.", target)
+		}
+		if _, e := env.TagLabelToTag(ctx, ruby); e != nil {
+			return noRubyError(ctx, ruby)
+		}
+		dir, e := gemsetDirName(ctx, ruby, gemset)
+		if e != nil {
+			return e
+		}
+		rootDir = filepath.Dir(filepath.Dir(dir))
+
+		all, e := gemsetSubtrees(rootDir)
+		if e != nil {
+			return e
+		}
+		for _, st := range all {
+			if st.dir == dir {
+				subtrees = append(subtrees, st)
+			}
+		}
+		if len(subtrees) == 0 {
+			return fmt.Errorf("---> no `%s` gemset for ruby matching `%s`", gemset, ruby)
+		}
+	}
+
+	fmt.Fprintf(ctx.Stdout, "---> gemset `%s` contains\n\n", rootDir)
+	for _, st := range subtrees {
+		fmt.Fprintf(ctx.Stdout, "  %-6s %-8s  %10s  %s\n", st.engine, st.version, formatSize(st.size), st.dir)
+	}
+
+	if dryRun {
+		fmt.Fprintln(ctx.Stdout, "\n---> dry run; nothing removed")
+		return
+	}
+
+	ok, err := ctx.Confirm("\nDelete the listed gemset dirs?")
 	if err != nil {
+		return confirmError(err)
+	}
+	if !ok {
 		return
 	}
 
-	if rv == `Y` {
-		fmt.Println("---> removing the current project's gemset")
+	if target == `` {
+		ctx.Infof("---> removing the current project's gemset\n")
+		return os.RemoveAll(rootDir)
+	}
 
-		rootDir, err = os.Getwd()
-		if err != nil {
+	ctx.Infof("---> removing `%s` gemset subtree\n", target)
+	for _, st := range subtrees {
+		if err = os.RemoveAll(st.dir); err != nil {
 			return
 		}
-		err = os.RemoveAll(filepath.Join(rootDir, `.gem`))
+		// prune the engine and gemset root dirs once empty
+		for _, d := range []string{filepath.Dir(st.dir), rootDir} {
+			if e := os.Remove(d); e != nil {
+				log.Debugf("not removing `%s`: %v\n", d, e)
+				break
+			}
+		}
 	}
 
 	return
 }
+
+// gemsetSubtrees returns the $ENGINE/$RUBY_LIB_VERSION subtrees of a gemset
+// root dir, or an error if the root dir does not exist or contains anything
+// other than the uru gemset layout.
+func gemsetSubtrees(rootDir string) (subtrees []gemsetSubtree, err error) {
+	notGemset := fmt.Errorf("---> `%s` does not follow the uru gemset layout; not removing", rootDir)
+
+	engines, err := ioutil.ReadDir(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("---> no gemset found at `%s`", rootDir)
+	}
+	for _, e := range engines {
+		if !e.IsDir() {
+			return nil, notGemset
+		}
+		engineDir := filepath.Join(rootDir, e.Name())
+		versions, err := ioutil.ReadDir(engineDir)
+		if err != nil {
+			return nil, notGemset
+		}
+		for _, v := range versions {
+			if !v.IsDir() || !gemsetVersionRegex.MatchString(v.Name()) {
+				return nil, notGemset
+			}
+			st := gemsetSubtree{engine: e.Name(), version: v.Name(), dir: filepath.Join(engineDir, v.Name())}
+			st.size = dirSize(st.dir)
+			subtrees = append(subtrees, st)
+		}
+	}
+
+	return
+}
+
+// dirSize returns the total size of the regular files in a directory tree.
+func dirSize(dir string) (size int64) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return
+}
+
+// formatSize returns a human readable representation of a size in bytes.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,209 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestGemsetDir(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(filepath.Join(`uru`, `home`))
+	cwd, _ := os.Getwd()
+
+	var tests = []struct {
+		Ruby   env.Ruby
+		Gemset string
+		Want   string
+	}{
+		{env.Ruby{Exe: `ruby`, ID: `3.2.2-p53`}, `gemset`, filepath.Join(cwd, `.gem`, `ruby`, `3.2.0`)},
+		{env.Ruby{Exe: `ruby`, ID: `2.0.0-p648`}, `gemset`, filepath.Join(cwd, `.gem`, `ruby`, `2.0.0`)},
+		{env.Ruby{Exe: `jruby`, ID: `9.4.2.0`}, `rails7`, filepath.Join(`uru`, `home`, `gemsets`, `rails7`, `jruby`, `9.4.0`)},
+	}
+
+	for _, v := range tests {
+		got, err := gemsetDir(ctx, v.Ruby, v.Gemset)
+		if err != nil {
+			t.Error("gemsetDir() returned error")
+		}
+		if got != v.Want {
+			t.Errorf("gemsetDir() not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.Want, got)
+		}
+	}
+}
+
+func TestActiveGemset(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(filepath.Join(`uru`, `home`))
+	rb := env.Ruby{Exe: `ruby`, ID: `3.2.2-p53`, GemHome: filepath.Join(`home`, `.gem`, `ruby`, `3.2.0`)}
+
+	orig := os.Getenv(`GEM_HOME`)
+	defer os.Setenv(`GEM_HOME`, orig)
+
+	var tests = []struct {
+		GemHome string
+		Want    string
+	}{
+		{``, ``},
+		{rb.GemHome, ``},
+		{filepath.Join(`project`, `.gem`, `ruby`, `3.2.0`), `gemset`},
+		{filepath.Join(`uru`, `home`, `gemsets`, `rails7`, `ruby`, `3.2.0`), `rails7`},
+		{filepath.Join(`uru`, `home`, `gemsets`, `rails7`, `ruby`, `3.1.0`), ``},
+		{filepath.Join(`elsewhere`, `gems`), ``},
+	}
+
+	for _, v := range tests {
+		os.Setenv(`GEM_HOME`, v.GemHome)
+		if got, _ := activeGemset(ctx, rb); got != v.Want {
+			t.Errorf("activeGemset() not returning correct value for `%s`\n  want: `%v`\n  got: `%v`",
+				v.GemHome, v.Want, got)
+		}
+	}
+}
+
+func TestGemsetSubtrees(t *testing.T) {
+	root, err := ioutil.TempDir(``, `uru-gemset`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	gemDir := filepath.Join(root, `.gem`)
+	os.MkdirAll(filepath.Join(gemDir, `ruby`, `3.2.0`, `gems`), 0750)
+	os.MkdirAll(filepath.Join(gemDir, `jruby`, `3.1.0`), 0750)
+	ioutil.WriteFile(filepath.Join(gemDir, `ruby`, `3.2.0`, `gems`, `a.rb`), []byte(`12345`), 0640)
+
+	subtrees, err := gemsetSubtrees(gemDir)
+	if err != nil {
+		t.Fatalf("gemsetSubtrees() returned error: %v", err)
+	}
+	if len(subtrees) != 2 {
+		t.Fatalf("gemsetSubtrees() not returning correct number of subtrees\n  want: `2`\n  got: `%d`",
+			len(subtrees))
+	}
+	for _, st := range subtrees {
+		if st.engine == `ruby` && st.size != 5 {
+			t.Errorf("gemsetSubtrees() not returning correct size\n  want: `5`\n  got: `%d`", st.size)
+		}
+	}
+
+	// anything other than $ENGINE/$RUBY_LIB_VERSION dirs is not a gemset
+	ioutil.WriteFile(filepath.Join(gemDir, `credentials`), []byte(`secret`), 0600)
+	if _, err = gemsetSubtrees(gemDir); err == nil {
+		t.Error("gemsetSubtrees() not returning error for non-gemset layout")
+	}
+	if _, err = gemsetSubtrees(filepath.Join(root, `missing`)); err == nil {
+		t.Error("gemsetSubtrees() not returning error for missing dir")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	var tests = []struct {
+		Size int64
+		Want string
+	}{
+		{0, `0 B`},
+		{1023, `1023 B`},
+		{1536, `1.5 KiB`},
+		{5 * 1024 * 1024, `5.0 MiB`},
+	}
+
+	for _, v := range tests {
+		if got := formatSize(v.Size); got != v.Want {
+			t.Errorf("formatSize() not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.Want, got)
+		}
+	}
+}
+
+func TestGemsetListJSON(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	os.MkdirAll(filepath.Join(ctx.Home(), `gemsets`, `rails7`, `ruby`, `3.2.0`), 0750)
+	os.MkdirAll(filepath.Join(ctx.Home(), `gemsets`, `rails7`, `jruby`, `9.4.0`), 0750)
+	os.MkdirAll(filepath.Join(ctx.Home(), `gemsets`, `empty`), 0750)
+
+	var out bytes.Buffer
+	ctx.Stdout = &out
+	ctx.SetCmdAndArgs(`admin`, []string{`gemset`, `ls`, `--format`, `json`})
+	if err := CmdRouter.Dispatch(ctx, `admin`); err != nil {
+		t.Fatalf("admin gemset ls --format json returned error: %v", err)
+	}
+
+	var doc struct {
+		SchemaVersion int            `json:"schema_version"`
+		Kind          string         `json:"kind"`
+		Items         []gemsetRecord `json:"items"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("admin gemset ls --format json not writing valid JSON: %v\n%s", err, out.String())
+	}
+	want := []gemsetRecord{
+		{Name: `empty`, Type: `named`, Dir: filepath.Join(ctx.Home(), `gemsets`, `empty`), Rubies: []string{}},
+		{Name: `rails7`, Type: `named`, Dir: filepath.Join(ctx.Home(), `gemsets`, `rails7`),
+			Rubies: []string{`jruby 9.4.0`, `ruby 3.2.0`}},
+	}
+	if doc.SchemaVersion != OutputSchemaVersion || doc.Kind != `gemsets` || !reflect.DeepEqual(doc.Items, want) {
+		t.Errorf("admin gemset ls --format json not returning correct document\n  want: `%+v`\n  got: `%+v`",
+			want, doc)
+	}
+}
+
+func TestAdminGemsetInitFailures(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Registry.Rubies = env.RubyMap{
+		`1264043201`: {TagLabel: `322p53`, ID: `3.2.2-p53`, Exe: `ruby`},
+	}
+	var stderr strings.Builder
+	ctx.Stdout, ctx.Stderr = &strings.Builder{}, &stderr
+	ctx.SetCmdAndArgs(`admin`, []string{`gemset`, `init`, `bogus@x`, `322@rails7`})
+
+	err := CmdRouter.Dispatch(ctx, `admin`)
+	if got := ExitCode(err); got != ExitError {
+		t.Errorf("admin gemset init not returning correct exit code\n  want: `%d`\n  got: `%d`", ExitError, got)
+	}
+	if !strings.Contains(stderr.String(), `bogus`) {
+		t.Errorf("admin gemset init not reporting the failed name\n  got: `%s`", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(ctx.Home(), `gemsets`, `rails7`, `ruby`, `3.2.0`)); err != nil {
+		t.Error("admin gemset init not initializing the remaining gemsets after a failure")
+	}
+}
+
+func TestGemsetRemoveTargetErrors(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Stdout = &strings.Builder{}
+	ctx.Registry.Rubies = env.RubyMap{
+		`1264043201`: {TagLabel: `322p53`, ID: `3.2.2-p53`, Exe: `ruby`},
+		`2447330651`: {TagLabel: `323p100`, ID: `3.2.3-p100`, Exe: `ruby`},
+	}
+
+	var tests = []struct {
+		target string
+		want   string
+	}{
+		{`32@rails7`, `unable to find ruby specific to`},
+		{`42@rails7`, `unable to find registered ruby matching`},
+	}
+
+	for _, v := range tests {
+		ctx.SetCmdAndArgs(`admin`, []string{`gemset`, `rm`, v.target})
+		if err := CmdRouter.Dispatch(ctx, `admin`); err == nil || !strings.Contains(err.Error(), v.want) {
+			t.Errorf("admin gemset rm not returning correct error for `%s`\n  want: `%s`\n  got: `%v`",
+				v.target, v.want, err)
+		}
+	}
+}
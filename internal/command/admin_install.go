@@ -6,6 +6,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -20,24 +21,39 @@ var adminInstallCmd *Command = &Command{
 	Usage:   "admin install",
 	Eg:      "admin install",
 	Short:   "install uru",
-	Run:     adminInstall,
+	Long: `Writes the uru shell function wrapping uru_rt to stdout. The wrapper lets uru
+change the environment of the calling shell when switching rubies, so it must
+be loaded by each new shell, typically from a shell startup file:
+
+    echo 'eval "$(uru_rt admin install)"' >> ~/.bash_profile
+
+A fish function is written when SHELL is fish. uru_rt must be in a dir on
+PATH.`,
+	Flags: []Flag{},
+	Run:   adminInstall,
 }
 
 func init() {
 	adminRouter.Handle(adminInstallCmd.Aliases, adminInstallCmd)
 }
 
-func adminInstall(ctx *env.Context) {
+func adminInstall(ctx *env.Context) error {
 	if _, err := exec.LookPath("uru_rt"); err != nil {
-		fmt.Printf("[ERROR] uru_rt must be present in a directory on PATH\n")
-		os.Exit(1)
+		return errors.New("[ERROR] uru_rt must be present in a directory on PATH")
 	}
 
 	switch sh := os.Getenv("SHELL"); {
 	default:
-		fmt.Printf(env.BashWrapper)
+		fmt.Fprint(ctx.Stdout, env.BashWrapper)
 	case strings.Contains(sh, "fish"):
-		fmt.Printf(env.FishWrapper)
+		fmt.Fprint(ctx.Stdout, env.FishWrapper)
 	}
 
+	return nil
+}
+
+// installedWrappers returns the wrapper scripts written by `admin install`. The
+// uru shell functions are loaded by shell startup files instead.
+func installedWrappers() []string {
+	return nil
 }
@@ -4,13 +4,15 @@
 package command
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 var adminInstallCmd *Command = &Command{
@@ -19,17 +21,24 @@ var adminInstallCmd *Command = &Command{
 	Usage:   "admin install",
 	Eg:      "admin install",
 	Short:   "install uru",
-	Run:     adminInstall,
+	Long: `Installs the uru.bat and uru.ps1 wrapper scripts next to uru_rt.exe for
+cmd.exe and PowerShell, backing up any existing wrappers. Run it from the dir
+containing uru_rt.exe, which must be on PATH. The wrappers let uru change the
+environment of the calling shell when switching rubies.
+
+In bash-like shells such as Cygwin and MSYS2, the uru shell function wrapping
+uru_rt.exe is written to stdout instead, just like on Linux and OS X.`,
+	Flags: []Flag{},
+	Run:   adminInstall,
 }
 
 func init() {
 	adminRouter.Handle(adminInstallCmd.Aliases, adminInstallCmd)
 }
 
-func adminInstall(ctx *env.Context) {
+func adminInstall(ctx *env.Context) error {
 	if _, err := exec.LookPath("uru_rt.exe"); err != nil {
-		fmt.Printf("[ERROR] uru_rt.exe must be present in a directory on PATH\n")
-		os.Exit(1)
+		return errors.New("[ERROR] uru_rt.exe must be present in a directory on PATH")
 	}
 
 	// generate uru wrapper shell function on stdout for bash-like and fish shells
@@ -37,23 +46,22 @@ func adminInstall(ctx *env.Context) {
 	if shlvl := os.Getenv("SHLVL"); shlvl != `` {
 		switch sh := os.Getenv("SHELL"); {
 		default:
-			fmt.Printf(env.BashWrapper)
+			fmt.Fprint(ctx.Stdout, env.BashWrapper)
 		case strings.Contains(sh, "fish"):
-			fmt.Printf(env.FishWrapper)
+			fmt.Fprint(ctx.Stdout, env.FishWrapper)
 		}
-		return
+		return nil
 	}
 
 	if _, err := os.Stat("uru_rt.exe"); os.IsNotExist(err) {
-		fmt.Printf("[ERROR] must install from same directory as uru_rt.exe\n")
-		os.Exit(1)
+		return errors.New("[ERROR] must install from same directory as uru_rt.exe")
 	}
 
 	for _, v := range []string{"uru.bat", "uru.ps1"} {
 		if _, err := os.Stat(v); err == nil {
-			log.Printf("[DEBUG] creating backup of `%s`\n", v)
+			log.Debugf("creating backup of `%s`\n", v)
 			if _, e := env.CopyFile(fmt.Sprintf("%s.bak", v), v); e != nil {
-				log.Printf("[DEBUG] failed to backup `%s`; continuing", v)
+				log.Warn("backup failed; continuing", "file", v)
 			}
 		}
 	}
@@ -64,17 +72,31 @@ func adminInstall(ctx *env.Context) {
 	} else {
 		cwd = fmt.Sprintf("into %s", cwd)
 	}
-	fmt.Printf("---> Installing uru %s\n", cwd)
+	ctx.Infof("---> Installing uru %s\n", cwd)
 
 	for k, v := range map[string]string{"uru.bat": env.BatWrapper, "uru.ps1": env.PSWrapper} {
 		script, err := os.Create(k)
 		if err != nil {
-			panic(fmt.Sprintf("unable to create `%s` script wrapper", k))
+			return fmt.Errorf("[ERROR] unable to create `%s` script wrapper", k)
 		}
 		defer script.Close()
 
 		if _, err = script.WriteString(v); err != nil {
-			panic(fmt.Sprintf("failed to write `%s` script wrapper", k))
+			return fmt.Errorf("[ERROR] failed to write `%s` script wrapper", k)
 		}
 	}
+
+	return nil
+}
+
+// installedWrappers returns the wrapper scripts written by `admin install`
+// next to uru_rt.exe.
+func installedWrappers() []string {
+	exe, err := exec.LookPath("uru_rt.exe")
+	if err != nil {
+		return nil
+	}
+
+	dir := filepath.Dir(exe)
+	return []string{filepath.Join(dir, "uru.bat"), filepath.Join(dir, "uru.ps1")}
 }
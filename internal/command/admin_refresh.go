@@ -4,13 +4,14 @@
 package command
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 var adminRefreshCmd *Command = &Command{
@@ -19,29 +20,29 @@ var adminRefreshCmd *Command = &Command{
 	Usage:   "admin refresh [--retag]",
 	Eg:      "admin refresh",
 	Short:   "refresh all registered rubies",
-	Run:     adminRefresh,
+	Long: `Runs each registered ruby to refresh its registered version, description and
+gem home, for example after upgrading a ruby in place. Rubies that no longer
+exist, or that fail to run, are deregistered. Tag labels are kept unless given
+--retag, which replaces them with labels generated from the fresh versions.`,
+	Flags: []Flag{
+		{Name: `retag`, Usage: "replace tag labels with freshly generated defaults"},
+	},
+	Run: adminRefresh,
 }
 
 func init() {
 	adminRouter.Handle(adminRefreshCmd.Aliases, adminRefreshCmd)
 }
 
-func adminRefresh(ctx *env.Context) {
-
-	retag := false
-	for _, v := range ctx.CmdArgs() {
-		if v == `--retag` {
-			retag = true
-			break
-		}
-	}
+func adminRefresh(ctx *env.Context) error {
+	retag := ctx.IsFlagSet(`retag`)
 
 	freshRubies := make(env.RubyMap, 4)
 
 	for _, info := range ctx.Registry.Rubies {
 		_, err := os.Stat(info.Home)
 		if os.IsNotExist(err) {
-			fmt.Printf("---> %s tagged as `%s` does not exist; deregistering\n",
+			fmt.Fprintf(ctx.Stdout, "---> %s tagged as `%s` does not exist; deregistering\n",
 				info.Exe, info.TagLabel)
 			continue
 		}
@@ -50,7 +51,7 @@ func adminRefresh(ctx *env.Context) {
 
 		newTagHash, freshInfo, err := env.RubyInfo(ctx, rb)
 		if err != nil {
-			fmt.Printf("---> unable to refresh %s tagged as `%s`; deregistering\n",
+			fmt.Fprintf(ctx.Stdout, "---> unable to refresh %s tagged as `%s`; deregistering\n",
 				info.Exe, info.TagLabel)
 			continue
 		}
@@ -72,16 +73,17 @@ func adminRefresh(ctx *env.Context) {
 			freshInfo.GemHome = info.GemHome
 		}
 
-		fmt.Printf("---> refreshing %s tagged as `%s`\n", info.Exe, info.TagLabel)
+		ctx.Infof("---> refreshing %s tagged as `%s`\n", info.Exe, info.TagLabel)
 		freshRubies[newTagHash] = freshInfo
 	}
 
-	log.Printf("[DEBUG] === fresh ruby metadata ===\n%+v\n", freshRubies)
+	log.Tracef("=== fresh ruby metadata ===\n%+v\n", freshRubies)
 	ctx.Registry.Rubies = freshRubies
 
 	err := ctx.Registry.Marshal(ctx)
 	if err != nil {
-		fmt.Println("---> unable to persist refreshed ruby metadata")
-		os.Exit(1)
+		return errors.New("---> unable to persist refreshed ruby metadata")
 	}
+
+	return nil
 }
@@ -4,8 +4,8 @@
 package command
 
 import (
+	"errors"
 	"fmt"
-	"os"
 
 	"bitbucket.org/jonforums/uru/internal/env"
 )
@@ -16,33 +16,34 @@ var adminRetagCmd *Command = &Command{
 	Usage:   "admin retag CURRENT NEW",
 	Eg:      "admin retag 217p376 217p376-x64",
 	Short:   "retag CURRENT tag value to NEW",
-	Run:     adminRetag,
+	Long: `Changes the tag label of the registered ruby identified by CURRENT to NEW.
+When CURRENT matches several rubies, uru asks which one to retag. NEW may not
+be one of the labels uru reserves, such as 'auto' or 'nil'.`,
+	Flags: []Flag{},
+	Run:   adminRetag,
 }
 
 func init() {
 	adminRouter.Handle(adminRetagCmd.Aliases, adminRetagCmd)
 }
 
-func adminRetag(ctx *env.Context) {
+func adminRetag(ctx *env.Context) error {
 	cmdArgs := ctx.CmdArgs()
 	if len(cmdArgs) != 2 {
-		fmt.Println("[ERROR] must specify both CURRENT and NEW tag labels")
-		os.Exit(1)
+		return errors.New("[ERROR] must specify both CURRENT and NEW tag labels")
 	}
 
 	oldLabel, newLabel := cmdArgs[0], cmdArgs[1]
 
 	for _, ri := range ctx.Registry.Rubies {
 		if newLabel == ri.TagLabel {
-			fmt.Printf("---> `%s` collides with an existing registered ruby\n", newLabel)
-			os.Exit(1)
+			return fmt.Errorf("---> `%s` collides with an existing registered ruby", newLabel)
 		}
 	}
 
 	tags, err := env.TagLabelToTag(ctx, oldLabel)
 	if err != nil {
-		fmt.Printf("---> unable to find registered ruby matching `%s`\n", oldLabel)
-		os.Exit(1)
+		return noRubyError(ctx, oldLabel)
 	}
 
 	tagHash := ``
@@ -55,9 +56,9 @@ func adminRetag(ctx *env.Context) {
 	} else {
 		// multiple rubies match the given tag label, ask the user for the
 		// correct one.
-		tagHash, err = env.SelectRubyFromList(tags, oldLabel, `retag`)
+		tagHash, err = env.SelectRubyFromList(ctx, tags, oldLabel, `retag`)
 		if err != nil {
-			os.Exit(1)
+			return selectionError(err)
 		}
 	}
 
@@ -65,8 +66,7 @@ func adminRetag(ctx *env.Context) {
 	origLabel := rb.TagLabel
 
 	if rsvd, word := isTagLabelReserved(newLabel); rsvd == true {
-		fmt.Printf("---> Tag label `%s` conflicts with reserved `%s`. Try again\n", newLabel, word)
-		os.Exit(1)
+		return fmt.Errorf("---> Tag label `%s` conflicts with reserved `%s`. Try again", newLabel, word)
 	}
 
 	rb.TagLabel = newLabel
@@ -74,8 +74,10 @@ func adminRetag(ctx *env.Context) {
 
 	err = ctx.Registry.Marshal(ctx)
 	if err != nil {
-		fmt.Printf("---> Failed to retag `%s` to `%s`. Try again", origLabel, newLabel)
+		return fmt.Errorf("---> Failed to retag `%s` to `%s`. Try again", origLabel, newLabel)
 	}
 
-	fmt.Printf("---> retagged `%s` to `%s`\n", origLabel, newLabel)
+	ctx.Infof("---> retagged `%s` to `%s`\n", origLabel, newLabel)
+
+	return nil
 }
@@ -4,8 +4,8 @@
 package command
 
 import (
+	"errors"
 	"fmt"
-	"os"
 
 	"bitbucket.org/jonforums/uru/internal/env"
 )
@@ -16,45 +16,47 @@ var adminRemoveCmd *Command = &Command{
 	Usage:   "admin rm TAG | --all",
 	Eg:      "admin rm 193p193",
 	Short:   "deregister a ruby installation from uru",
-	Run:     adminRemove,
+	Long: `Deregisters the ruby identified by TAG, or with --all every registered ruby,
+after asking for confirmation. When TAG matches several rubies, uru asks which
+one to deregister. The ruby installation itself isn't touched.
+
+When stdin isn't a terminal nothing is deregistered unless the global --yes
+option is given.`,
+	Flags: []Flag{
+		{Name: `all`, Usage: "deregister all rubies"},
+	},
+	Run: adminRemove,
 }
 
 func init() {
 	adminRouter.Handle(adminRemoveCmd.Aliases, adminRemoveCmd)
 }
 
-func adminRemove(ctx *env.Context) {
-	if len(ctx.CmdArgs()) == 0 {
-		fmt.Println("[ERROR] must specify the tag of the ruby to deregister")
-		os.Exit(1)
+func adminRemove(ctx *env.Context) error {
+	rmAll := ctx.IsFlagSet(`all`)
+	if len(ctx.CmdArgs()) == 0 && !rmAll {
+		return errors.New("[ERROR] must specify the tag of the ruby to deregister")
 	}
 
-	var rmAll bool
 	var tagLabel string
-	for _, v := range ctx.CmdArgs() {
-		if v == `--all` {
-			rmAll = true
-			tagLabel = `all`
-			break
-		}
+	if rmAll {
+		tagLabel = `all`
 	}
 
 	if rmAll {
-		resp, err := env.UIYesConfirm("\nOK to deregister all rubies?")
+		ok, err := ctx.Confirm("\nOK to deregister all rubies?")
 		if err != nil {
-			fmt.Println("---> Unable to understand your response. Try again")
-			return
+			return confirmError(err)
 		}
-		if resp == `N` {
-			return
+		if !ok {
+			return nil
 		}
 		ctx.Registry.Rubies = make(env.RubyMap, 4)
 	} else {
 		tagLabel = ctx.CmdArgs()[0]
 		tags, err := env.TagLabelToTag(ctx, tagLabel)
 		if err != nil {
-			fmt.Printf("---> unable to find registered ruby matching `%s`\n", tagLabel)
-			os.Exit(1)
+			return noRubyError(ctx, tagLabel)
 		}
 
 		tagHash := ``
@@ -67,21 +69,20 @@ func adminRemove(ctx *env.Context) {
 		} else {
 			// multiple rubies match the given tag label, ask the user for the
 			// correct one.
-			tagHash, err = env.SelectRubyFromList(tags, tagLabel, `deregister`)
+			tagHash, err = env.SelectRubyFromList(ctx, tags, tagLabel, `deregister`)
 			if err != nil {
-				os.Exit(1)
+				return selectionError(err)
 			}
 		}
 
 		rb := ctx.Registry.Rubies[tagHash]
 
-		resp, err := env.UIYesConfirm(fmt.Sprintf("\nOK to deregister `%s`?", rb.Description))
+		ok, err := ctx.Confirm(fmt.Sprintf("\nOK to deregister `%s`?", rb.Description))
 		if err != nil {
-			fmt.Println("---> Unable to understand your response. Try again")
-			return
+			return confirmError(err)
 		}
-		if resp == `N` {
-			return
+		if !ok {
+			return nil
 		}
 
 		delete(ctx.Registry.Rubies, tagHash)
@@ -89,6 +90,8 @@ func adminRemove(ctx *env.Context) {
 
 	err := ctx.Registry.Marshal(ctx)
 	if err != nil {
-		fmt.Printf("---> Failed to remove `%s`. Try again", tagLabel)
+		return fmt.Errorf("---> Failed to remove `%s`. Try again", tagLabel)
 	}
+
+	return nil
 }
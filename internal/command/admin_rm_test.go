@@ -0,0 +1,64 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// fakeConfirmer answers confirmation prompts without asking the user.
+type fakeConfirmer struct {
+	yes     bool
+	err     error
+	prompts []string
+}
+
+func (c *fakeConfirmer) Confirm(prompt string) (bool, error) {
+	c.prompts = append(c.prompts, prompt)
+	return c.yes, c.err
+}
+
+func TestAdminRemoveConfirm(t *testing.T) {
+	var tests = []struct {
+		args    []string
+		confirm *fakeConfirmer
+		want    int // number of rubies still registered
+		errMsg  string
+	}{
+		{[]string{`rm`, `942`}, &fakeConfirmer{yes: true}, 1, ``},
+		{[]string{`rm`, `942`}, &fakeConfirmer{}, 2, ``},
+		{[]string{`rm`, `--all`}, &fakeConfirmer{yes: true}, 0, ``},
+		{[]string{`rm`, `--all`}, &fakeConfirmer{err: env.ErrNotInteractive}, 2, `not a terminal`},
+		{[]string{`rm`, `942`}, &fakeConfirmer{err: env.ErrNoInput}, 2, `--no-input`},
+	}
+
+	for _, v := range tests {
+		ctx := env.NewContext()
+		ctx.SetHome(t.TempDir())
+		ctx.Stdout, ctx.Confirmer = &strings.Builder{}, v.confirm
+		ctx.Registry.Rubies = env.RubyMap{
+			`3574260220`: env.Ruby{ID: `2.2.3-p146`, TagLabel: `223p146`, Exe: `ruby`, Home: `/rubies/ruby-2.2.3/bin`},
+			`1078060107`: env.Ruby{ID: `9.4.2`, TagLabel: `942`, Exe: `jruby`, Home: `/rubies/jruby-9.4.2/bin`},
+		}
+		ctx.SetCmdAndArgs(`admin`, v.args)
+
+		err := CmdRouter.Dispatch(ctx, `admin`)
+		if got := len(ctx.Registry.Rubies); got != v.want {
+			t.Errorf("admin %v not removing correct rubies\n  want: `%d` registered\n  got: `%d` registered",
+				v.args, v.want, got)
+		}
+		if len(v.confirm.prompts) != 1 {
+			t.Errorf("admin %v not asking for confirmation\n  got: `%v`", v.args, v.confirm.prompts)
+		}
+		switch {
+		case v.errMsg == `` && err != nil:
+			t.Errorf("admin %v returned error: %v", v.args, err)
+		case v.errMsg != `` && (err == nil || !strings.Contains(err.Error(), v.errMsg)):
+			t.Errorf("admin %v not returning correct error\n  want: `%s`\n  got: `%v`", v.args, v.errMsg, err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
+)
+
+var adminUninstallCmd *Command = &Command{
+	Name:    "uninstall",
+	Aliases: []string{"uninstall"},
+	Usage:   "admin uninstall [--keep-registry]",
+	Eg:      "admin uninstall --keep-registry",
+	Short:   "uninstall uru",
+	Long: `Reverses 'uru admin install'. Removes the wrapper scripts and completion
+scripts generated by uru, and the lines loading uru, such as
+
+    eval "$(uru_rt admin install)"
+
+from the startup files of bash, zsh, fish and PowerShell, after listing them
+and asking for confirmation. A backup of each changed startup file is saved
+with a .uru.bak extension.
+
+Uru then lists the contents of its home dir, i.e. the registry of rubies,
+switcher scripts, config, plugins and named gemsets, and asks whether to
+delete it. The --keep-registry option keeps the registry so the rubies don't
+have to be registered again after reinstalling uru. The uru_rt executable
+itself isn't removed.`,
+	Flags: []Flag{
+		{Name: `keep-registry`, Usage: "don't delete the registry of rubies"},
+	},
+	Run: adminUninstall,
+}
+
+// Matches the lines of shell startup files that load the uru wrapper or uru's
+// completion scripts, as documented by `admin install` and `admin completion`.
+var uruStartupLineRegex *regexp.Regexp
+
+// Matches the header of the wrapper and completion scripts generated by uru.
+var uruGeneratedRegex *regexp.Regexp
+
+func init() {
+	adminRouter.Handle(adminUninstallCmd.Aliases, adminUninstallCmd)
+
+	var err error
+	uruStartupLineRegex, err = regexp.Compile(`\buru_rt(\.exe)?\s+admin\s+(install|in|completion)\b`)
+	if err != nil {
+		panic("unable to compile uru startup line regexp")
+	}
+	uruGeneratedRegex, err = regexp.Compile("autogenerated by `?uru")
+	if err != nil {
+		panic("unable to compile uru generated script regexp")
+	}
+}
+
+// Files in uru's home dir kept by `--keep-registry`.
+var registryFiles = []string{`rubies.json`, `rubies.json.bak`}
+
+// startupEdit is a shell startup file along with the uru lines to remove.
+type startupEdit struct {
+	file  string
+	lines []string
+}
+
+func adminUninstall(ctx *env.Context) error {
+	if len(ctx.CmdArgs()) != 0 {
+		return errors.New("[ERROR] invalid `admin uninstall [--keep-registry]` invocation.")
+	}
+	keepRegistry := ctx.IsFlagSet(`keep-registry`)
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return errors.New("---> unable to determine the user's home dir")
+	}
+
+	edits := uruStartupLines(startupFiles(userHome))
+	scripts := generatedScripts(append(installedWrappers(),
+		filepath.Join(userHome, `.config`, `fish`, `completions`, `uru.fish`)))
+	if err = removeUruStartup(ctx, edits, scripts); err != nil {
+		return err
+	}
+
+	if err = removeUruHome(ctx, userHome, keepRegistry); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.Stdout, "---> restart your shells to finish uninstalling uru; `uru_rt` wasn't removed")
+	return nil
+}
+
+// startupFiles returns the startup files of the shells uru supports that may
+// load uru.
+func startupFiles(userHome string) []string {
+	files := []string{
+		filepath.Join(userHome, `.bash_profile`),
+		filepath.Join(userHome, `.bashrc`),
+		filepath.Join(userHome, `.profile`),
+		filepath.Join(userHome, `.zshenv`),
+		filepath.Join(userHome, `.zprofile`),
+		filepath.Join(userHome, `.zshrc`),
+		filepath.Join(userHome, `.config`, `fish`, `config.fish`),
+		filepath.Join(userHome, `.config`, `powershell`, `Microsoft.PowerShell_profile.ps1`),
+		filepath.Join(userHome, `Documents`, `PowerShell`, `Microsoft.PowerShell_profile.ps1`),
+		filepath.Join(userHome, `Documents`, `WindowsPowerShell`, `Microsoft.PowerShell_profile.ps1`),
+	}
+	if zdot := os.Getenv(`ZDOTDIR`); zdot != `` && zdot != userHome {
+		for _, f := range []string{`.zshenv`, `.zprofile`, `.zshrc`} {
+			files = append(files, filepath.Join(zdot, f))
+		}
+	}
+
+	return files
+}
+
+// uruStartupLines returns the lines loading uru found in the startup files.
+// Missing or unreadable files are skipped.
+func uruStartupLines(files []string) (edits []startupEdit) {
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+
+		edit := startupEdit{file: f}
+		sc := bufio.NewScanner(strings.NewReader(string(b)))
+		for sc.Scan() {
+			if l := sc.Text(); uruStartupLineRegex.MatchString(l) {
+				edit.lines = append(edit.lines, l)
+			}
+		}
+		if len(edit.lines) > 0 {
+			edits = append(edits, edit)
+		}
+	}
+
+	return
+}
+
+// generatedScripts returns the existing files generated by uru.
+func generatedScripts(files []string) (scripts []string) {
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		// the marker is in the first or, after `@echo off`, second line
+		head := strings.SplitN(string(b), "\n", 3)
+		if len(head) > 2 {
+			head = head[:2]
+		}
+		if uruGeneratedRegex.MatchString(strings.Join(head, "\n")) {
+			scripts = append(scripts, f)
+		}
+	}
+
+	return
+}
+
+// removeUruStartup removes the uru lines from the shell startup files, and the
+// scripts generated by uru, after asking the user for confirmation.
+func removeUruStartup(ctx *env.Context, edits []startupEdit, scripts []string) error {
+	if len(edits) == 0 && len(scripts) == 0 {
+		ctx.Infof("---> no uru wrappers or shell startup lines found\n")
+		return nil
+	}
+
+	fmt.Fprintln(ctx.Stdout, "---> uru wrappers and shell startup lines to remove")
+	for _, e := range edits {
+		fmt.Fprintf(ctx.Stdout, "\n  %s\n", e.file)
+		for _, l := range e.lines {
+			fmt.Fprintf(ctx.Stdout, "    %s\n", strings.TrimSpace(l))
+		}
+	}
+	if len(scripts) > 0 {
+		fmt.Fprintln(ctx.Stdout)
+	}
+	for _, s := range scripts {
+		fmt.Fprintf(ctx.Stdout, "  %s\n", s)
+	}
+
+	ok, err := ctx.Confirm("\nRemove the listed wrappers and lines?")
+	if err != nil {
+		return confirmError(err)
+	}
+	if !ok {
+		return nil
+	}
+
+	for _, e := range edits {
+		if err := removeStartupLines(e.file); err != nil {
+			log.Warn("unable to edit startup file", "file", e.file, "err", err)
+			return fmt.Errorf("[ERROR] unable to remove the uru lines from `%s`", e.file)
+		}
+		ctx.Infof("---> removed the uru lines from `%s`\n", e.file)
+	}
+	for _, s := range scripts {
+		if err := os.Remove(s); err != nil {
+			log.Warn("unable to remove script", "file", s, "err", err)
+			return fmt.Errorf("[ERROR] unable to remove `%s`", s)
+		}
+		ctx.Infof("---> removed `%s`\n", s)
+	}
+
+	return nil
+}
+
+// removeStartupLines removes the lines loading uru from a startup file after
+// saving a `.uru.bak` backup of the file.
+func removeStartupLines(file string) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if _, err = env.CopyFile(fmt.Sprintf("%s.uru.bak", file), file); err != nil {
+		return err
+	}
+
+	var kept []string
+	for _, l := range strings.SplitAfter(string(b), "\n") {
+		if !uruStartupLineRegex.MatchString(l) {
+			kept = append(kept, l)
+		}
+	}
+
+	return ioutil.WriteFile(file, []byte(strings.Join(kept, ``)), fi.Mode().Perm())
+}
+
+// removeUruHome lists the contents of uru's home dir and deletes it, or with
+// keepRegistry everything but the registry, after asking the user for
+// confirmation.
+func removeUruHome(ctx *env.Context, userHome string, keepRegistry bool) error {
+	home := ctx.Home()
+	entries, err := ioutil.ReadDir(home)
+	if err != nil {
+		ctx.Infof("---> no uru home dir found at `%s`\n", home)
+		return nil
+	}
+
+	abs, err := filepath.Abs(home)
+	if err != nil || abs == filepath.Dir(abs) || abs == userHome {
+		return fmt.Errorf("[ERROR] refusing to delete `%s`; is URU_HOME correct?", home)
+	}
+
+	var doomed []os.FileInfo
+	for _, e := range entries {
+		if !(keepRegistry && isRegistryFile(e.Name())) {
+			doomed = append(doomed, e)
+		}
+	}
+	if len(doomed) == 0 {
+		ctx.Infof("---> nothing to delete in uru's home dir `%s`\n", home)
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\n---> uru home dir `%s` contains\n\n", home)
+	sort.Slice(doomed, func(i, j int) bool { return doomed[i].Name() < doomed[j].Name() })
+	for _, e := range doomed {
+		size := e.Size()
+		if e.IsDir() {
+			size = dirSize(filepath.Join(home, e.Name()))
+		}
+		fmt.Fprintf(ctx.Stdout, "  %-20s %10s  %s\n", e.Name(), formatSize(size), uruHomeEntryInfo(ctx, e))
+	}
+
+	prompt := fmt.Sprintf("\nDelete uru's home dir `%s`?", home)
+	if keepRegistry {
+		prompt = fmt.Sprintf("\nDelete the listed files, keeping the registry in `%s`?", home)
+	}
+	ok, err := ctx.Confirm(prompt)
+	if err != nil {
+		return confirmError(err)
+	}
+	if !ok {
+		return nil
+	}
+
+	if !keepRegistry {
+		ctx.Infof("---> deleting `%s`\n", home)
+		return os.RemoveAll(home)
+	}
+	for _, e := range doomed {
+		if err := os.RemoveAll(filepath.Join(home, e.Name())); err != nil {
+			log.Warn("unable to delete", "file", e.Name(), "err", err)
+			return fmt.Errorf("[ERROR] unable to delete `%s` from `%s`", e.Name(), home)
+		}
+	}
+	ctx.Infof("---> deleted all but the registry from `%s`\n", home)
+
+	return nil
+}
+
+func isRegistryFile(name string) bool {
+	for _, f := range registryFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// uruHomeEntryInfo returns a description of a file or dir in uru's home dir.
+func uruHomeEntryInfo(ctx *env.Context, fi os.FileInfo) string {
+	name := fi.Name()
+	switch {
+	case name == `rubies.json`:
+		return fmt.Sprintf("registry of %d rubies", len(ctx.Registry.Rubies))
+	case name == `rubies.json.bak`:
+		return "registry backup"
+	case name == `gemsets` && fi.IsDir():
+		var names []string
+		if gs, err := ioutil.ReadDir(filepath.Join(ctx.Home(), name)); err == nil {
+			for _, g := range gs {
+				names = append(names, g.Name())
+			}
+		}
+		return fmt.Sprintf("named gemsets: %s", strings.Join(names, `, `))
+	case name == `plugins` && fi.IsDir():
+		return "plugins"
+	case name == `config`:
+		return "config, e.g. aliases"
+	case name == `uru.log`:
+		return "log"
+	case strings.HasPrefix(name, `uru_lackee`):
+		return "switcher script"
+	}
+
+	return ``
+}
@@ -0,0 +1,120 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+const testBashrc = `export EDITOR=vim
+eval "$(uru_rt admin install)"
+source <(uru_rt admin completion bash)
+alias ll='ls -l'
+`
+
+func TestAdminUninstall(t *testing.T) {
+	setup := func(confirm *fakeConfirmer, args ...string) (*env.Context, string) {
+		userHome := t.TempDir()
+		t.Setenv(`HOME`, userHome)
+		t.Setenv(`USERPROFILE`, userHome)
+		t.Setenv(`ZDOTDIR`, ``)
+
+		files := map[string]string{
+			`.bashrc`:                           testBashrc,
+			`.config/fish/config.fish`:          "set -x EDITOR vim\n",
+			`.config/fish/completions/uru.fish`: fishCompletion,
+			`.config/fish/completions/git.fish`: "# git completion\n",
+			`.uru/rubies.json`:                  `{"Version":"1.0.0","Rubies":{}}`,
+			`.uru/uru_lackee`:                   "export PATH=/rubies/bin\n",
+			`.uru/gemsets/rails7/ruby/3.2.0/x`:  "x",
+		}
+		for name, content := range files {
+			p := filepath.Join(userHome, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(p), 0755)
+			if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ctx := env.NewContext()
+		ctx.SetHome(filepath.Join(userHome, `.uru`))
+		ctx.Stdout, ctx.Confirmer = &strings.Builder{}, confirm
+		ctx.SetCmdAndArgs(`admin`, append([]string{`uninstall`}, args...))
+		return ctx, userHome
+	}
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+
+	confirm := &fakeConfirmer{yes: true}
+	ctx, userHome := setup(confirm)
+	if err := CmdRouter.Dispatch(ctx, `admin`); err != nil {
+		t.Fatalf("admin uninstall returned error: %v", err)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(userHome, `.bashrc`))
+	if want := "export EDITOR=vim\nalias ll='ls -l'\n"; string(b) != want {
+		t.Errorf("admin uninstall not removing the uru startup lines\n  want: `%q`\n  got: `%q`", want, b)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(userHome, `.bashrc.uru.bak`)); string(b) != testBashrc {
+		t.Errorf("admin uninstall not backing up the changed startup file\n  got: `%q`", b)
+	}
+	if exists(filepath.Join(userHome, `.config/fish/config.fish.uru.bak`)) {
+		t.Error("admin uninstall changing a startup file without uru lines")
+	}
+	if exists(filepath.Join(userHome, `.config/fish/completions/uru.fish`)) {
+		t.Error("admin uninstall not removing the generated fish completion script")
+	}
+	if !exists(filepath.Join(userHome, `.config/fish/completions/git.fish`)) {
+		t.Error("admin uninstall removing a script not generated by uru")
+	}
+	if exists(ctx.Home()) {
+		t.Error("admin uninstall not deleting uru's home dir")
+	}
+	if len(confirm.prompts) != 2 {
+		t.Errorf("admin uninstall not asking for confirmation twice\n  got: `%v`", confirm.prompts)
+	}
+
+	ctx, _ = setup(&fakeConfirmer{yes: true}, `--keep-registry`)
+	if err := CmdRouter.Dispatch(ctx, `admin`); err != nil {
+		t.Fatalf("admin uninstall --keep-registry returned error: %v", err)
+	}
+	entries, _ := ioutil.ReadDir(ctx.Home())
+	if len(entries) != 1 || entries[0].Name() != `rubies.json` {
+		t.Errorf("admin uninstall --keep-registry not keeping only the registry\n  got: `%v`", entries)
+	}
+
+	ctx, userHome = setup(&fakeConfirmer{err: env.ErrNotInteractive})
+	if err := CmdRouter.Dispatch(ctx, `admin`); err == nil || !strings.Contains(err.Error(), `not a terminal`) {
+		t.Errorf("admin uninstall not failing when unable to ask for confirmation\n  got: `%v`", err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(userHome, `.bashrc`)); string(b) != testBashrc || !exists(ctx.Home()) {
+		t.Error("admin uninstall changing files without confirmation")
+	}
+}
+
+func TestAdminUninstallRefusesUserHome(t *testing.T) {
+	userHome := t.TempDir()
+	t.Setenv(`HOME`, userHome)
+	t.Setenv(`USERPROFILE`, userHome)
+	ioutil.WriteFile(filepath.Join(userHome, `notes.txt`), []byte("keep me"), 0644)
+
+	ctx := env.NewContext()
+	ctx.SetHome(userHome)
+	ctx.Stdout, ctx.Confirmer = &strings.Builder{}, &fakeConfirmer{yes: true}
+	ctx.SetCmdAndArgs(`admin`, []string{`uninstall`})
+
+	if err := CmdRouter.Dispatch(ctx, `admin`); err == nil || !strings.Contains(err.Error(), `refusing`) {
+		t.Errorf("admin uninstall not refusing to delete the user's home dir\n  got: `%v`", err)
+	}
+	if _, err := os.Stat(filepath.Join(userHome, `notes.txt`)); err != nil {
+		t.Error("admin uninstall deleting files in the user's home dir")
+	}
+}
@@ -0,0 +1,144 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// Config file section defining user command aliases.
+const aliasSection = `alias`
+
+// ExpandAlias expands a user alias defined in the `[alias]` section of uru's
+// config file into the command and args it stands for, e.g.
+//
+//    [alias]
+//    t = exec auto -- rake test
+//    latest = 322
+//    each = matrix --order version $@ -- ruby -v
+//
+// The args given after an alias are appended to its expansion unless the alias
+// places them itself using `$@` for all args or `$1`...`$9` for a single arg.
+// Aliases may expand to other aliases but never to themselves, and builtin
+// commands can't be aliased. Commands that aren't aliases are returned as is.
+func ExpandAlias(ctx *env.Context, cmd string, args []string) (string, []string, error) {
+	var chain []string
+	seen := make(map[string]bool)
+
+	for {
+		if _, err := CmdRouter.Handler(cmd); err == nil {
+			return cmd, args, nil
+		}
+		def, ok := ctx.Config.Get(aliasSection, cmd)
+		if !ok {
+			return cmd, args, nil
+		}
+
+		chain = append(chain, cmd)
+		if seen[strings.ToLower(cmd)] {
+			return ``, nil, fmt.Errorf("recursive alias `%s`", strings.Join(chain, ` -> `))
+		}
+		seen[strings.ToLower(cmd)] = true
+
+		words, err := splitAliasWords(def)
+		if err != nil {
+			return ``, nil, fmt.Errorf("invalid alias `%s`: %v", cmd, err)
+		}
+		if len(words) == 0 {
+			return ``, nil, fmt.Errorf("empty alias `%s`", cmd)
+		}
+		words = expandAliasArgs(words, args)
+
+		cmd, args = words[0], words[1:]
+	}
+}
+
+// expandAliasArgs replaces the `$@` and `$1`...`$9` placeholders of the words
+// of an alias with the given args, or appends the args if the alias has no
+// placeholders. Placeholders of missing args expand to nothing.
+func expandAliasArgs(words, args []string) []string {
+	var expanded []string
+	placed := false
+
+	for _, w := range words {
+		switch {
+		case w == `$@`:
+			expanded = append(expanded, args...)
+			placed = true
+		case len(w) == 2 && w[0] == '$' && w[1] >= '1' && w[1] <= '9':
+			if i, _ := strconv.Atoi(w[1:]); i <= len(args) {
+				expanded = append(expanded, args[i-1])
+			}
+			placed = true
+		default:
+			expanded = append(expanded, w)
+		}
+	}
+	if !placed {
+		expanded = append(expanded, args...)
+	}
+
+	return expanded
+}
+
+// splitAliasWords splits an alias definition into words separated by white
+// space. Single or double quotes group words containing white space.
+func splitAliasWords(s string) (words []string, err error) {
+	var word strings.Builder
+	var quote rune
+	inWord := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return
+}
+
+// aliasCommands returns a command describing each user alias, sorted by name.
+func aliasCommands(ctx *env.Context) (aliases []*Command) {
+	for _, name := range ctx.Config.Keys(aliasSection) {
+		if _, err := CmdRouter.Handler(name); err == nil {
+			continue
+		}
+		def, _ := ctx.Config.Get(aliasSection, name)
+		aliases = append(aliases, &Command{
+			Name:    name,
+			Aliases: []string{name},
+			Usage:   fmt.Sprintf("%s ARGS...", name),
+			Eg:      name,
+			Short:   fmt.Sprintf("alias for `%s`", def),
+		})
+	}
+
+	return
+}
@@ -0,0 +1,97 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func aliasContext(t *testing.T, cfg string) *env.Context {
+	ctx := env.NewContext()
+	c, err := env.ReadConfig(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatalf("unable to read test config: %v", err)
+	}
+	ctx.Config = c
+
+	return ctx
+}
+
+func TestExpandAlias(t *testing.T) {
+	ctx := aliasContext(t, `[alias]
+t = exec auto -- rake test
+latest = 322
+rt = t
+each = matrix --order version $@ -- ruby -v
+one = exec $2 -- ruby $1
+quoted = exec auto -- ruby -e 'puts "hi there"'
+ls = ls --verbose
+`)
+
+	tests := []struct {
+		cmd      string
+		args     []string
+		wantCmd  string
+		wantArgs []string
+	}{
+		{`t`, nil, `exec`, []string{`auto`, `--`, `rake`, `test`}},
+		{`t`, []string{`TEST=x`}, `exec`, []string{`auto`, `--`, `rake`, `test`, `TEST=x`}},
+		{`rt`, []string{`-v`}, `exec`, []string{`auto`, `--`, `rake`, `test`, `-v`}},
+		{`latest`, nil, `322`, []string{}},
+		{`each`, []string{`--only`, `32`}, `matrix`, []string{`--order`, `version`, `--only`, `32`, `--`, `ruby`, `-v`}},
+		{`one`, []string{`-w`, `322`}, `exec`, []string{`322`, `--`, `ruby`, `-w`}},
+		{`one`, []string{`-w`}, `exec`, []string{`--`, `ruby`, `-w`}},
+		{`quoted`, nil, `exec`, []string{`auto`, `--`, `ruby`, `-e`, `puts "hi there"`}},
+		{`ls`, []string{`x`}, `ls`, []string{`x`}},
+		{`322`, []string{`x`}, `322`, []string{`x`}},
+	}
+
+	for _, tt := range tests {
+		cmd, args, err := ExpandAlias(ctx, tt.cmd, tt.args)
+		if err != nil {
+			t.Errorf("ExpandAlias(%s) unexpected error: %v", tt.cmd, err)
+			continue
+		}
+		if args == nil {
+			args = []string{}
+		}
+		if tt.wantArgs == nil {
+			tt.wantArgs = []string{}
+		}
+		if cmd != tt.wantCmd || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("ExpandAlias(%s) not returning correct value\n  want: `%v %v`\n  got: `%v %v`",
+				tt.cmd,
+				tt.wantCmd, tt.wantArgs,
+				cmd, args)
+		}
+	}
+}
+
+func TestExpandAliasErrors(t *testing.T) {
+	ctx := aliasContext(t, `[alias]
+a = b x
+b = c
+c = a
+self = self
+empty =
+bad = exec 'auto
+`)
+
+	for _, name := range []string{`a`, `self`, `empty`, `bad`} {
+		if _, _, err := ExpandAlias(ctx, name, nil); err == nil {
+			t.Errorf("ExpandAlias(%s) not returning an error", name)
+		}
+	}
+
+	_, _, err := ExpandAlias(ctx, `a`, nil)
+	if want := "recursive alias `a -> b -> c -> a`"; err == nil || err.Error() != want {
+		t.Errorf("ExpandAlias() not returning correct error\n  want: `%v`\n  got: `%v`",
+			want,
+			err)
+	}
+}
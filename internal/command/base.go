@@ -4,18 +4,32 @@
 package command
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
-var CmdRouter *Router = NewRouter(use)
+var CmdRouter *Router = NewRouter(nil)
+
+func init() {
+	// set here rather than by NewRouter as the default handler refers back to
+	// CmdRouter when suggesting commands similar to a mistyped command
+	CmdRouter.defHandler = pluginOrUse
+}
 
 func isTagLabelReserved(tagLabel string) (bool, string) {
 	resTagLabels := []string{`auto`, `nil`}
@@ -44,7 +58,7 @@ func parseGemsetName(rawName string) (ruby, gemset string, err error) {
 		namesLen = len(names)
 	}
 
-	log.Printf("[DEBUG] === gemset names array ===\n  names: %v\n  namesLen: %d\n", names, namesLen)
+	log.Tracef("=== gemset names array ===\n  names: %v\n  namesLen: %d\n", names, namesLen)
 
 	switch namesLen {
 	case 1:
@@ -58,69 +72,514 @@ func parseGemsetName(rawName string) (ruby, gemset string, err error) {
 	return
 }
 
-func rubyExec(ctx *env.Context) (err error) {
-	// TODO error check for empty PATH string
-	curPath := os.Getenv(`PATH`)
-	curGemHome := os.Getenv(`GEM_HOME`)
+// Exit codes returned by uru. Commands which run a program with a single
+// ruby, such as `exec`, instead exit with the exit code of the program.
+const (
+	// success, including a program that succeeded with every selected ruby
+	ExitOK = iota
+
+	// uru itself failed, e.g. invalid usage or no matching registered rubies
+	ExitError
+
+	// a program run with multiple rubies returned a non-zero exit code for
+	// at least one ruby
+	ExitRubyFailed
+
+	// a program run with multiple rubies could not be started for at least
+	// one ruby, e.g. the program was not found on that ruby's PATH
+	ExitRubyError
+)
+
+// ExitCode returns the uru exit code for an error returned by a command: the
+// code reported by the error's ExitCode method, if any, otherwise ExitError.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var e interface{ ExitCode() int }
+	if errors.As(err, &e) {
+		return e.ExitCode()
+	}
+
+	return ExitError
+}
+
+// exitStatus is the error returned by commands, such as `exec`, that exit with
+// the non-zero exit code of a program they ran. The program reports its own
+// failure so the error has an empty message.
+type exitStatus int
+
+func (e exitStatus) Error() string { return `` }
+func (e exitStatus) ExitCode() int { return int(e) }
+
+// exitWith returns the error for a program's exit code; nil for success.
+func exitWith(code int) error {
+	if code == 0 {
+		return nil
+	}
+	return exitStatus(code)
+}
+
+// selectionError returns the error for a failed selection of one of several
+// rubies matching a tag label. Exiting the selection without picking a ruby
+// simply fails, while `--no-input` explains why the user wasn't asked.
+func selectionError(err error) error {
+	if errors.Is(err, env.ErrNoInput) {
+		return fmt.Errorf("[ERROR] %v", err)
+	}
+	return exitStatus(ExitError)
+}
+
+// confirmError returns the error for a confirmation the user couldn't be
+// asked, e.g. because stdin isn't a terminal.
+func confirmError(err error) error {
+	return fmt.Errorf("[ERROR] %v", err)
+}
+
+// multiRubyOptions are the options given by the flags of multi-ruby commands
+// such as `uru ruby` and `uru gem`.
+type multiRubyOptions struct {
+	selector rubySelector // registered rubies to run
+	reports  []reportSpec // reports to generate from the results
+	failFast bool         // stop running rubies after the first failure
+	jobs     int          // maximum number of concurrently running rubies
+}
+
+// multiRubyFlags returns the flags of a multi-ruby command: the SELECT_OPTS
+// flags followed by the command's own flags.
+func multiRubyFlags(flags ...Flag) []Flag {
+	return append(append([]Flag{}, selectOptsFlags...), flags...)
+}
+
+// parseMultiRubyFlags returns the options given by the flags of a multi-ruby
+// command, running up to jobs rubies concurrently unless `--jobs` is given.
+func parseMultiRubyFlags(ctx *env.Context, jobs int) (opts multiRubyOptions, err error) {
+	opts.jobs, opts.failFast = jobs, ctx.IsFlagSet(`fail-fast`)
+
+	if ctx.IsFlagSet(`jobs`) {
+		v := ctx.Flag(`jobs`)
+		if opts.jobs, err = strconv.Atoi(v); err != nil || opts.jobs < 1 {
+			return opts, fmt.Errorf("[ERROR] invalid `--jobs %s` value.", v)
+		}
+	}
+	for _, v := range ctx.FlagValues(`report`) {
+		rs, e := parseReportSpec(v)
+		if e != nil {
+			return opts, e
+		}
+		opts.reports = append(opts.reports, rs)
+	}
+	opts.selector, err = selectorFromFlags(ctx)
+
+	return
+}
+
+// multiRubyError summarizes the rubies for which a program run with multiple
+// rubies failed.
+type multiRubyError struct {
+	total  int
+	failed []*rubyResult
+}
+
+func (e *multiRubyError) Error() string {
+	var fails []string
+	for _, r := range e.failed {
+		if r.Err != nil {
+			fails = append(fails, fmt.Sprintf("%s (%s)", r.Ruby.TagLabel, r.Err))
+		} else {
+			fails = append(fails, fmt.Sprintf("%s (exit %d)", r.Ruby.TagLabel, r.ExitCode))
+		}
+	}
+
+	return fmt.Sprintf("---> failed with %d of %d rubies: %s", len(e.failed), e.total,
+		strings.Join(fails, ", "))
+}
+
+// ExitCode returns the uru exit code corresponding to the failed runs.
+func (e *multiRubyError) ExitCode() int {
+	for _, r := range e.failed {
+		if r.Err != nil {
+			return ExitRubyError
+		}
+	}
+	return ExitRubyFailed
+}
+
+// resultsError returns a *multiRubyError describing the failed results, or nil
+// if the program succeeded with every ruby.
+func resultsError(results []*rubyResult) error {
+	e := &multiRubyError{total: len(results)}
+	for _, r := range results {
+		if !r.Passed() {
+			e.failed = append(e.failed, r)
+		}
+	}
+	if len(e.failed) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// multiRubyExec implements the user visible `ruby` and `gem` commands which run
+// the context's command with the registered rubies selected by the command's
+// flags, writing any reports requested by `--report FORMAT=PATH`. The
+// returned *multiRubyError maps to one of the documented exit codes if the
+// command fails for any ruby.
+func multiRubyExec(ctx *env.Context) error {
+	opts, err := parseMultiRubyFlags(ctx, 1)
+	if err != nil {
+		return err
+	}
 
-	for tagHash, info := range ctx.Registry.Rubies {
-		fmt.Printf("\n%s\n\n", info.Description)
+	tagHashes, err := selectRubies(ctx, &opts.selector)
+	if err != nil {
+		return err
+	}
+
+	var results []*rubyResult
+	var runErr error
+	if opts.jobs > 1 {
+		var mu sync.Mutex
+		results, runErr = rubyExecConcurrent(ctx, tagHashes, opts.jobs, opts.failFast,
+			func(r *rubyResult) {
+				mu.Lock()
+				defer mu.Unlock()
+				printRubyResult(ctx, r)
+			})
+	} else {
+		results, runErr = rubyExec(ctx, tagHashes, opts.failFast)
+	}
+
+	cmdLine := strings.Join(append([]string{ctx.Cmd()}, ctx.CmdArgs()...), " ")
+	if err = writeReports(opts.reports, cmdLine, results); err != nil {
+		return err
+	}
+
+	return runErr
+}
+
+// rubyExec runs the context's command with each of the registered rubies
+// identified by the tag hashes, in the given order, and returns the result of
+// each run along with a *multiRubyError if the command failed for any ruby.
+// When failFast is true, no further rubies are run after the first failure.
+// The child processes share uru's stdin, stdout and stderr, with each child's
+// stderr also being captured.
+func rubyExec(ctx *env.Context, tagHashes []string, failFast bool) (results []*rubyResult, err error) {
+	for _, tagHash := range tagHashes {
+		info := ctx.Registry.Rubies[tagHash]
+		fmt.Fprintf(ctx.Stdout, "\n%s\n\n", info.Description)
+
+		res := &rubyResult{TagHash: tagHash, Ruby: info}
+		results = append(results, res)
 
-		pth, err := env.PathListForTagHash(ctx, tagHash)
+		environ, pth, err := rubyEnviron(ctx, tagHash)
 		if err != nil {
-			fmt.Printf("[ERROR] getting path list, unable to run `%s %s`\n\n", ctx.Cmd(),
+			fmt.Fprintf(ctx.Stdout, "[ERROR] getting path list, unable to run `%s %s`\n\n", ctx.Cmd(),
 				strings.Join(ctx.CmdArgs(), " "))
-			break
+			res.Err = err
+			if failFast {
+				break
+			}
+			continue
 		}
 
-		// set env vars in current process to be inherited by the child process
-		if err = os.Setenv(`PATH`, strings.Join(pth, string(os.PathListSeparator))); err != nil {
-			fmt.Printf("[ERROR] setting PATH, unable to run `%s %s`\n\n", ctx.Cmd(),
-				strings.Join(ctx.CmdArgs(), " "))
-			break
+		// run the command in a child process configured with the ruby's own
+		// environment, leaving uru's environment untouched
+		cmd, cmdArgs := rubyCommand(info, ctx.Cmd(), ctx.CmdArgs())
+		exe, err := findExecutable(cmd, pth)
+		if err != nil {
+			res.Err, res.ExitCode = err, execNotFoundExitCode
+			fmt.Fprintf(ctx.Stdout, "---> unable to find `%s` for ruby tagged as `%s`\n\n", cmd, info.TagLabel)
+			if failFast {
+				break
+			}
+			continue
 		}
-		if info.GemHome != `` {
-			// XXX oddly, GEM_HOME must be set in current process so that users .gemrc
-			// is consulted. Setting os/exec's `Command.Env` causes users .gemrc to
-			// be ignored.
-			if err = os.Setenv(`GEM_HOME`, info.GemHome); err != nil {
-				fmt.Printf("[ERROR] setting GEM_HOME, unable to run `%s %s`\n\n", ctx.Cmd(),
-					strings.Join(ctx.CmdArgs(), " "))
+		log.Debug("spawn", "ruby", info.TagLabel, "exe", exe, "args", cmdArgs)
+
+		var stderr bytes.Buffer
+		runner := exec.Command(exe, cmdArgs...)
+		runner.Env = environ
+		runner.Stdin = ctx.Stdin
+		runner.Stdout = ctx.Stdout
+		runner.Stderr = io.MultiWriter(ctx.Stderr, &stderr)
+
+		start := time.Now()
+		err = runner.Run()
+		res.Duration = time.Since(start)
+		res.Stderr = stderr.Bytes()
+		log.Debug("exit", "ruby", info.TagLabel, "exe", exe, "code", runner.ProcessState.ExitCode(), "duration", res.Duration)
+
+		if err != nil {
+			res.setRunError(err)
+			fmt.Fprintf(ctx.Stdout, "---> unable to run `%s %s`\n\n", ctx.Cmd(),
+				strings.Join(ctx.CmdArgs(), " "))
+			log.Warn("run failed", "ruby", info.TagLabel, "exe", exe, "err", err)
+			if failFast {
 				break
 			}
 		}
+	}
 
-		// run the command in a child process and capture stdout/stderr
-		cmd := ctx.Cmd()
-		if runtime.GOOS == `windows` || cmd == `ruby` {
-			// on windows, bypass .bat wrappers; always select correct ruby exe
-			cmd = info.Exe
+	return results, resultsError(results)
+}
+
+// rubyExecConcurrent runs the context's command with each of the registered
+// rubies identified by the tag hashes, running at most jobs rubies at the same
+// time. Each child's output is captured rather than shared with uru, and the
+// done func, if not nil, is called as each ruby finishes. The returned results
+// are in the same order as the given tag hashes. When failFast is true, rubies
+// not yet started when a ruby fails are skipped and omitted from the results.
+func rubyExecConcurrent(ctx *env.Context, tagHashes []string, jobs int, failFast bool, done func(*rubyResult)) ([]*rubyResult, error) {
+	results := make([]*rubyResult, len(tagHashes))
+	sem := make(chan struct{}, jobs)
+
+	var failed int32
+	var wg sync.WaitGroup
+	for i, t := range tagHashes {
+		// start rubies in the given order
+		sem <- struct{}{}
+		if failFast && atomic.LoadInt32(&failed) != 0 {
+			<-sem
+			break
 		}
-		cmdArgs := ctx.CmdArgs()
-		if runtime.GOOS == `windows` && ctx.Cmd() == `gem` {
-			// on windows, bypass gem.bat wrapper; always run gem via ruby exe
-			cmdArgs = append([]string{filepath.Join(info.Home, `gem`)}, cmdArgs...)
+
+		wg.Add(1)
+		go func(i int, tagHash string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r := runRubyCaptured(ctx, tagHash, ctx.Cmd(), ctx.CmdArgs())
+			if !r.Passed() {
+				atomic.StoreInt32(&failed, 1)
+			}
+			results[i] = r
+			if done != nil {
+				done(r)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	ran := results[:0]
+	for _, r := range results {
+		if r != nil {
+			ran = append(ran, r)
 		}
-		log.Printf("[DEBUG] === exec.Command args ===\n  cmd: %s\n  cmdArgs: %#v\n",
-			cmd, cmdArgs)
+	}
 
-		runner := exec.Command(cmd, cmdArgs...)
-		runner.Stdin = os.Stdin
-		runner.Stdout = os.Stdout
-		runner.Stderr = os.Stderr
+	return ran, resultsError(ran)
+}
 
-		if err = runner.Run(); err != nil {
-			fmt.Printf("---> unable to run `%s %s`\n\n", ctx.Cmd(),
-				strings.Join(ctx.CmdArgs(), " "))
-			log.Printf("[DEBUG] === returned error message ===\n%s\n\n", err.Error())
+// runRubyCaptured runs a command with the ruby identified by the tag hash in a
+// child process configured with its own environment, capturing the combined
+// stdout and stderr of the child.
+func runRubyCaptured(ctx *env.Context, tagHash, cmd string, cmdArgs []string) *rubyResult {
+	res := &rubyResult{TagHash: tagHash, Ruby: ctx.Registry.Rubies[tagHash]}
+
+	environ, pth, err := rubyEnviron(ctx, tagHash)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	cmd, cmdArgs = rubyCommand(res.Ruby, cmd, cmdArgs)
+	exe, err := findExecutable(cmd, pth)
+	if err != nil {
+		res.Err, res.ExitCode = err, execNotFoundExitCode
+		return res
+	}
+	log.Debug("spawn", "ruby", res.Ruby.TagLabel, "exe", exe, "args", cmdArgs)
+
+	var out, stderr bytes.Buffer
+	combined := &lockedWriter{w: &out}
+	runner := exec.Command(exe, cmdArgs...)
+	runner.Env = environ
+	runner.Stdout = combined
+	runner.Stderr = io.MultiWriter(combined, &stderr)
+
+	start := time.Now()
+	err = runner.Run()
+	res.Duration = time.Since(start)
+	res.Output, res.Stderr = out.Bytes(), stderr.Bytes()
+	log.Debug("exit", "ruby", res.Ruby.TagLabel, "exe", exe, "code", runner.ProcessState.ExitCode(), "duration", res.Duration)
+
+	if err != nil {
+		res.setRunError(err)
+		log.Warn("run failed", "ruby", res.Ruby.TagLabel, "exe", exe, "err", err)
+	}
+
+	return res
+}
+
+// printRubyResult displays the captured output of a ruby run by
+// rubyExecConcurrent as a single block headed by the ruby's tag label,
+// description, status and duration.
+func printRubyResult(ctx *env.Context, r *rubyResult) {
+	fmt.Fprintf(ctx.Stdout, "\n---> %s: %s [%s %v]\n\n", r.Ruby.TagLabel, r.Ruby.Description,
+		r.status(), r.Duration.Round(time.Millisecond))
+	ctx.Stdout.Write(r.Output)
+}
+
+// rubyCommand returns the executable and arguments used to run a `ruby` or
+// `gem` command with a particular registered ruby. Other commands are returned
+// unchanged.
+func rubyCommand(info env.Ruby, cmd string, cmdArgs []string) (string, []string) {
+	switch {
+	case runtime.GOOS == `windows` && cmd == `gem`:
+		// on windows, bypass gem.bat wrapper; always run gem via ruby exe
+		return info.Exe, append([]string{filepath.Join(info.Home, `gem`)}, cmdArgs...)
+	case cmd == `ruby`:
+		// always select correct ruby exe, bypassing .bat wrappers on windows
+		return info.Exe, cmdArgs
+	}
+
+	return cmd, cmdArgs
+}
+
+// rubyEnviron returns a copy of uru's environment in which PATH and GEM_HOME
+// are those of the ruby identified by the tag hash, along with the new PATH
+// list. It is used to configure child processes without modifying uru's own
+// environment so that multiple rubies can be run concurrently.
+//
+// All other variables are carried over unchanged, including those RubyGems
+// uses to find the user's configuration: HOME (USERPROFILE, HOMEDRIVE and
+// HOMEPATH on windows), GEMRC and XDG_CONFIG_HOME. If HOME is not set on
+// *nix, as can happen for cron jobs, it is set to the user's home directory
+// so the user's .gemrc is still consulted.
+func rubyEnviron(ctx *env.Context, tagHash string) (environ, pth []string, err error) {
+	pth, err = env.PathListForTagHash(ctx, tagHash)
+	if err != nil {
+		return
+	}
+	gemHome := ctx.Registry.Rubies[tagHash].GemHome
+
+	hasHome := false
+	for _, v := range os.Environ() {
+		name := strings.SplitN(v, `=`, 2)[0]
+		if runtime.GOOS == `windows` {
+			// windows envar names are case insensitive
+			name = strings.ToUpper(name)
+		}
+		switch name {
+		case `PATH`, `GEM_HOME`:
+			continue
+		case `HOME`:
+			hasHome = true
 		}
+		environ = append(environ, v)
 	}
 
-	// revert to the original ruby
-	os.Setenv(`PATH`, curPath)
-	if curGemHome != `` {
-		os.Setenv(`GEM_HOME`, curGemHome)
+	if !hasHome && runtime.GOOS != `windows` {
+		if u, e := user.Current(); e == nil && u.HomeDir != `` {
+			log.Debugf("HOME not set; using `%s` for child process\n", u.HomeDir)
+			environ = append(environ, fmt.Sprintf("HOME=%s", u.HomeDir))
+		}
+	}
+	environ = append(environ, fmt.Sprintf("PATH=%s", strings.Join(pth, string(os.PathListSeparator))))
+	if gemHome != `` {
+		environ = append(environ, fmt.Sprintf("GEM_HOME=%s", gemHome))
 	}
 
 	return
 }
+
+// findExecutable searches the given PATH list for an executable named cmd and
+// returns its absolute path. Unlike `exec.LookPath` it does not consult uru's
+// own PATH. On windows, the extensions listed in PATHEXT are tried when cmd
+// has no extension.
+func findExecutable(cmd string, pth []string) (string, error) {
+	exts := []string{``}
+	if runtime.GOOS == `windows` && filepath.Ext(cmd) == `` {
+		exts = nil
+		pathExt := os.Getenv(`PATHEXT`)
+		if pathExt == `` {
+			pathExt = `.com;.exe;.bat;.cmd`
+		}
+		for _, e := range strings.Split(strings.ToLower(pathExt), `;`) {
+			if e != `` {
+				exts = append(exts, e)
+			}
+		}
+	}
+
+	isExe := func(name string) bool {
+		fi, err := os.Stat(name)
+		if err != nil || fi.IsDir() {
+			return false
+		}
+		return runtime.GOOS == `windows` || fi.Mode()&0111 != 0
+	}
+
+	if strings.ContainsRune(cmd, os.PathSeparator) || strings.ContainsRune(cmd, '/') {
+		for _, e := range exts {
+			if isExe(cmd + e) {
+				return filepath.Abs(cmd + e)
+			}
+		}
+		return ``, fmt.Errorf("executable `%s` not found", cmd)
+	}
+
+	for _, dir := range pth {
+		if dir == `` {
+			continue
+		}
+		for _, e := range exts {
+			if name := filepath.Join(dir, cmd+e); isExe(name) {
+				return filepath.Abs(name)
+			}
+		}
+	}
+
+	return ``, fmt.Errorf("executable `%s` not found on PATH", cmd)
+}
+
+// rubyResult records the outcome of running a command with a single
+// registered ruby.
+type rubyResult struct {
+	TagHash  string
+	Ruby     env.Ruby
+	ExitCode int
+	Duration time.Duration
+	Output   []byte // combined stdout and stderr, if captured
+	Stderr   []byte // stderr, if captured
+	Err      error  // error preventing the command from running to completion
+}
+
+// Passed indicates whether the command completed with a zero exit code.
+func (r *rubyResult) Passed() bool { return r.Err == nil && r.ExitCode == 0 }
+
+func (r *rubyResult) status() string {
+	switch {
+	case r.Err != nil:
+		return `ERROR`
+	case r.ExitCode != 0:
+		return `FAIL`
+	}
+	return `pass`
+}
+
+// setRunError records the error returned from running the child process,
+// distinguishing a non-zero exit code from a failure to run.
+func (r *rubyResult) setRunError(err error) {
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() >= 0 {
+		r.ExitCode = exitErr.ExitCode()
+		return
+	}
+	r.Err = err
+}
+
+// lockedWriter serializes writes to an underlying writer shared by multiple
+// goroutines.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
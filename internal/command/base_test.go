@@ -4,9 +4,17 @@
 package command
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
-	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
 )
 
 var testGemsetNames = []struct {
@@ -30,11 +38,6 @@ var testTagLabels = []struct {
 	{`nil`, true, `nil`},
 }
 
-func init() {
-	// silence any logging done in the package files
-	log.SetOutput(ioutil.Discard)
-}
-
 func TestParseGemsetName(t *testing.T) {
 	for _, v := range testGemsetNames {
 		ruby, gemset, err := parseGemsetName(v.RawName)
@@ -71,3 +74,247 @@ func TestIsTagLabelReserved(t *testing.T) {
 		}
 	}
 }
+
+// flagsContext returns a context holding the flags and args of the command
+// line parsed by the command.
+func flagsContext(cmd *Command, args []string) (*env.Context, error) {
+	flags, rest, err := cmd.parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	ctx := env.NewContext()
+	ctx.SetFlags(flags)
+	ctx.SetCmdArgs(rest)
+
+	return ctx, nil
+}
+
+func TestParseMultiRubyFlags(t *testing.T) {
+	parse := func(args []string) (opts multiRubyOptions, rest []string, err error) {
+		ctx, err := flagsContext(gemCmd, args)
+		if err != nil {
+			return
+		}
+		opts, err = parseMultiRubyFlags(ctx, 1)
+		return opts, ctx.CmdArgs(), err
+	}
+
+	opts, rest, err := parse([]string{`--report`, `junit=out.xml`, `--fail-fast`,
+		`--report`, `json=out.json`, `--only`, `32`, `--only`, `31,27`, `install`, `--report`, `foo`})
+	if err != nil {
+		t.Fatalf("parseMultiRubyFlags() returned error for valid args: %v", err)
+	}
+	want := []reportSpec{{`junit`, `out.xml`}, {`json`, `out.json`}}
+	if !reflect.DeepEqual(opts.reports, want) {
+		t.Errorf("parseMultiRubyFlags() returning incorrect reports\n  want: `%v`\n  got: `%v`", want, opts.reports)
+	}
+	if !opts.failFast || opts.jobs != 1 {
+		t.Errorf("parseMultiRubyFlags() returning incorrect options\n  got: `%+v`", opts)
+	}
+	if wantOnly := []string{`32`, `31`, `27`}; !reflect.DeepEqual(opts.selector.only, wantOnly) {
+		t.Errorf("parseMultiRubyFlags() returning incorrect selector\n  want: `%v`\n  got: `%v`", wantOnly, opts.selector.only)
+	}
+	if wantRest := []string{`install`, `--report`, `foo`}; !reflect.DeepEqual(rest, wantRest) {
+		t.Errorf("parseMultiRubyFlags() returning incorrect args\n  want: `%v`\n  got: `%v`", wantRest, rest)
+	}
+
+	opts, rest, err = parse([]string{`--`, `--fail-fast`})
+	if err != nil || opts.failFast || !reflect.DeepEqual(rest, []string{`--fail-fast`}) {
+		t.Errorf("parseMultiRubyFlags() did not stop parsing at `--`\n  got: `%+v`, `%v`", opts, rest)
+	}
+
+	for _, v := range [][]string{
+		{`--version`},
+		{`--version`, `-e`, `1`},
+		{`--only`, `32`, `--version`},
+	} {
+		opts, rest, err = parse(v)
+		if err != nil || !reflect.DeepEqual(rest, v[len(v)-len(rest):]) || rest[0] != `--version` {
+			t.Errorf("parseMultiRubyFlags() not passing `--version` through for `%v`\n  got: `%v`, `%v`", v, rest, err)
+		}
+	}
+
+	for _, v := range [][]string{
+		{`--report`},
+		{`--report`, `junit`},
+		{`--report`, `junit=`},
+		{`--report`, `html=out.html`},
+		{`--jobs`, `0`, `install`},
+		{`--order`, `tag`, `--order`, `version`},
+		{`--jbos`, `2`, `install`},
+		{`--fail-fats`, `install`},
+		{`--olny`, `32`, `install`},
+	} {
+		if _, _, err = parse(v); err == nil {
+			t.Errorf("parseMultiRubyFlags() should return error for `%v`", v)
+		}
+	}
+}
+
+func TestResultsError(t *testing.T) {
+	passed := &rubyResult{Ruby: env.Ruby{TagLabel: `211p1`}}
+	failed := &rubyResult{Ruby: env.Ruby{TagLabel: `179`}, ExitCode: 2}
+	broken := &rubyResult{Ruby: env.Ruby{TagLabel: `1710`}, Err: errors.New("not found")}
+
+	if err := resultsError([]*rubyResult{passed}); err != nil {
+		t.Errorf("resultsError() should return nil when all rubies pass, got: `%v`", err)
+	}
+
+	tests := []struct {
+		results []*rubyResult
+		code    int
+	}{
+		{[]*rubyResult{passed, failed}, ExitRubyFailed},
+		{[]*rubyResult{failed, broken, passed}, ExitRubyError},
+	}
+	for _, tt := range tests {
+		err, ok := resultsError(tt.results).(*multiRubyError)
+		if !ok {
+			t.Errorf("resultsError() should return a *multiRubyError for failed rubies")
+			continue
+		}
+		if code := err.ExitCode(); code != tt.code {
+			t.Errorf("multiRubyError.ExitCode() incorrect\n  want: `%v`\n  got: `%v`", tt.code, code)
+		}
+		if !strings.Contains(err.Error(), `179 (exit 2)`) {
+			t.Errorf("multiRubyError.Error() missing failed ruby: `%v`", err)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, ExitOK},
+		{errors.New("[ERROR] usage"), ExitError},
+		{exitStatus(127), 127},
+		{fmt.Errorf("wrapped: %w", exitStatus(ExitRubyFailed)), ExitRubyFailed},
+		{&multiRubyError{failed: []*rubyResult{{ExitCode: 1}}}, ExitRubyFailed},
+	}
+
+	for _, tt := range tests {
+		if code := ExitCode(tt.err); code != tt.code {
+			t.Errorf("ExitCode(%#v) not returning correct value\n  want: `%v`\n  got: `%v`",
+				tt.err, tt.code, code)
+		}
+	}
+	if exitWith(0) != nil {
+		t.Error("exitWith(0) should return nil")
+	}
+}
+
+func TestRubyEnviron(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.Registry.Rubies = env.RubyMap{
+		`3577244517`: {
+			ID:       `2.1.1-p1`,
+			TagLabel: `211p1`,
+			Exe:      `ruby`,
+			Home:     `/home/fake/.rubies/ruby-2.1.0/bin`,
+			GemHome:  `/home/fake/.gem/ruby/2.1.0`,
+		},
+	}
+
+	origPath := os.Getenv(`PATH`)
+	os.Setenv(`GEMRC`, `/home/fake/.gemrc`)
+	defer os.Unsetenv(`GEMRC`)
+
+	environ, pth, err := rubyEnviron(ctx, `3577244517`)
+	if err != nil {
+		t.Fatalf("rubyEnviron() returned error: %v", err)
+	}
+	if os.Getenv(`PATH`) != origPath {
+		t.Error("rubyEnviron() modified uru's PATH")
+	}
+
+	vars := make(map[string][]string)
+	for _, v := range environ {
+		kv := strings.SplitN(v, `=`, 2)
+		vars[kv[0]] = append(vars[kv[0]], kv[1])
+	}
+	want := map[string]string{
+		`PATH`:     strings.Join(pth, string(os.PathListSeparator)),
+		`GEM_HOME`: `/home/fake/.gem/ruby/2.1.0`,
+		`GEMRC`:    `/home/fake/.gemrc`,
+	}
+	for k, v := range want {
+		if got := vars[k]; len(got) != 1 || got[0] != v {
+			t.Errorf("rubyEnviron() incorrect `%s` value\n  want: `%v`\n  got: `%v`", k, v, got)
+		}
+	}
+	if !strings.Contains(vars[`PATH`][0], `/home/fake/.rubies/ruby-2.1.0/bin`) {
+		t.Errorf("rubyEnviron() PATH missing ruby bindir: `%v`", vars[`PATH`][0])
+	}
+}
+
+func TestRubyExecEnvironError(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.Stdout = &strings.Builder{}
+	ctx.Registry.Rubies = env.RubyMap{
+		`3577244517`: {TagLabel: `211p1`, Exe: `ruby`, Home: `/home/fake/.rubies/ruby-2.1.1/bin`},
+		`1264043201`: {TagLabel: `322p53`, Exe: `ruby`, Home: `/home/fake/.rubies/ruby-3.2.2/bin`},
+	}
+	ctx.SetCmdAndArgs(`ruby`, []string{`-v`})
+	tagHashes := []string{`3577244517`, `1264043201`}
+
+	// an empty PATH makes building each ruby's environment fail
+	t.Setenv(`PATH`, ``)
+
+	for _, failFast := range []bool{false, true} {
+		want := 2
+		if failFast {
+			want = 1
+		}
+		results, err := rubyExec(ctx, tagHashes, failFast)
+		if len(results) != want {
+			t.Errorf("rubyExec() with failFast `%v` not running correct number of rubies\n  want: `%d`\n  got: `%d`",
+				failFast, want, len(results))
+		}
+		if ExitCode(err) != ExitRubyError {
+			t.Errorf("rubyExec() with failFast `%v` not returning correct exit code\n  want: `%d`\n  got: `%d`",
+				failFast, ExitRubyError, ExitCode(err))
+		}
+	}
+}
+
+func TestMultiRubyExecVersionPassthrough(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip("fake ruby scripts require a *nix shell")
+	}
+
+	bindir := t.TempDir()
+	for _, exe := range []string{`ruby`, `gem`} {
+		ioutil.WriteFile(filepath.Join(bindir, exe),
+			[]byte(fmt.Sprintf("#!/bin/sh\necho %s \"$@\"\n", exe)), 0750)
+	}
+
+	for _, v := range []struct {
+		cmd  string
+		args []string
+		want string
+	}{
+		{`ruby`, []string{`--version`}, "ruby --version\n"},
+		{`ruby`, []string{`--version`, `-e`, `1`}, "ruby --version -e 1\n"},
+		{`gem`, []string{`--version`}, "gem --version\n"},
+		{`gem`, []string{`--only`, `322p53`, `--version`}, "gem --version\n"},
+	} {
+		ctx := env.NewContext()
+		ctx.SetHome(t.TempDir())
+		ctx.Registry.Rubies = env.RubyMap{
+			`1264043201`: {TagLabel: `322p53`, Exe: `ruby`, Home: bindir, Description: `ruby 3.2.2p53`},
+		}
+		out := &strings.Builder{}
+		ctx.Stdout = out
+		ctx.SetCmdAndArgs(v.cmd, v.args)
+
+		if err := CmdRouter.Dispatch(ctx, v.cmd); err != nil {
+			t.Errorf("`%s %v` returned error: %v", v.cmd, v.args, err)
+			continue
+		}
+		if !strings.HasSuffix(out.String(), v.want) {
+			t.Errorf("`%s %v` not passing args through\n  want: `%q`\n  got: `%q`", v.cmd, v.args, v.want, out.String())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"io"
+	"os"
+	"runtime"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// ANSI escape sequences used to colorize output.
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+// useColor indicates whether output should be colorized. Color is only used
+// when writing to a terminal, never on Windows consoles, and never when the
+// `--no-color` option or NO_COLOR env var was given.
+func useColor(ctx *env.Context) bool {
+	return !ctx.Options.NoColor && runtime.GOOS != `windows` && isTerminal(ctx.Stdout)
+}
+
+// isTerminal indicates whether w writes to a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in the given color if output should be colorized.
+func colorize(ctx *env.Context, color, s string) string {
+	if !useColor(ctx) {
+		return s
+	}
+
+	return color + s + colorReset
+}
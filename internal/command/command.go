@@ -4,6 +4,9 @@
 package command
 
 import (
+	"fmt"
+	"strings"
+
 	"bitbucket.org/jonforums/uru/internal/env"
 )
 
@@ -29,10 +32,175 @@ type Command struct {
 	// Plugin command type flag
 	IsPlugin bool
 
-	// Function invoked by the command router
-	Run func(ctx *env.Context)
+	// Hidden commands are dispatched but not listed in help or completions
+	Hidden bool
+
+	// Command line flags parsed by the command router before invoking Run. A
+	// command with a nil Flags spec receives its arguments unparsed.
+	Flags []Flag
+
+	// Commands running a program, e.g. `ruby`, stop parsing their Flags at the
+	// first arg not naming one of the Flags, which starts the program's args.
+	// Args misspelling one of the Flags are rejected rather than passed on.
+	ProgramArgs bool
+
+	// Child router dispatching the sub-commands of this command. The first arg
+	// of a command having sub-commands names the sub-command to invoke.
+	Subcommands *Router
+
+	// Function invoked by the command router. A returned error is displayed and
+	// mapped to uru's exit code by ExitCode.
+	Run func(ctx *env.Context) error
 }
 
 // Runnable indicates whether this command can be invoked. Non runnable commands
 // are information only commands.
 func (t *Command) Runnable() bool { return t.Run != nil }
+
+// Flag describes a command line flag given as `--NAME` for boolean flags, or as
+// `--NAME VALUE` or `--NAME=VALUE` for flags taking a value.
+type Flag struct {
+	// Flag name without the leading `--`
+	Name string
+
+	// Value placeholder displayed in help, e.g. `TAG`; empty for boolean flags
+	Value string
+
+	// Flag may be given multiple times, accumulating its values
+	Repeated bool
+
+	// Single line summarizing this flag
+	Usage string
+}
+
+// String returns the flag as displayed in help, e.g. `--tag TAG`.
+func (f Flag) String() string {
+	if f.Value == `` {
+		return fmt.Sprintf("--%s", f.Name)
+	}
+	return fmt.Sprintf("--%s %s", f.Name, f.Value)
+}
+
+// GlobalFlags are uru's global options, parsed by uru's main package before
+// dispatching a command. They are given before the command, e.g.
+// `uru --yes admin rm 193p193`, except for the logging options which may be
+// given in any position.
+var GlobalFlags = []Flag{
+	{Name: `home`, Value: `DIR`, Usage: "use DIR as uru's home dir instead of URU_HOME"},
+	{Name: `shell`, Value: `NAME`, Usage: "write switcher scripts for shell NAME instead of URU_INVOKER"},
+	{Name: `quiet`, Usage: "don't display informational messages"},
+	{Name: `no-color`, Usage: "never colorize output"},
+	{Name: `json`, Usage: "display query results, e.g. of `ls`, as JSON"},
+	{Name: `yes`, Usage: "answer yes to all confirmation prompts"},
+	{Name: `no-input`, Usage: "fail instead of prompting for input"},
+	{Name: `log-level`, Value: `LEVEL`, Usage: "log at the error, warn, info, debug or trace LEVEL"},
+	{Name: `log-file`, Usage: "append the log to uru.log in uru's home dir"},
+}
+
+// globalFlag returns the global option named by a `--NAME` or `--NAME=VALUE`
+// command line arg.
+func globalFlag(arg string) (Flag, bool) {
+	name := strings.SplitN(strings.TrimPrefix(arg, `--`), `=`, 2)[0]
+	for _, f := range GlobalFlags {
+		if f.Name == name && strings.HasPrefix(arg, `--`) {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// parseFlags parses the command line flags of args according to the command's
+// Flags spec, returning the flag values indexed by flag name and the remaining
+// positional args. Flags and positional args may be intermixed, unless the
+// command takes ProgramArgs; all args following `--` are positional.
+func (t *Command) parseFlags(args []string) (flags map[string][]string, rest []string, err error) {
+	flags = make(map[string][]string)
+
+	argsLen := len(args)
+	for i := 0; i < argsLen; i++ {
+		arg := args[i]
+		if arg == `--` {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, `--`) {
+			if t.ProgramArgs {
+				rest = append(rest, args[i:]...)
+				return
+			}
+			rest = append(rest, arg)
+			continue
+		}
+
+		name, val := arg[2:], ``
+		hasVal := false
+		if j := strings.Index(name, `=`); j >= 0 {
+			name, val, hasVal = name[:j], name[j+1:], true
+		}
+
+		f, ok := t.flag(name)
+		switch {
+		case !ok && t.ProgramArgs:
+			if s := t.suggestFlags(name); len(s) > 0 {
+				return nil, nil, fmt.Errorf("[ERROR] unknown `%s` flag `%s`%s\n---> see `%s help %s` for the `%s` flags",
+					t.Name, arg, didYouMean(s), env.AppName, t.Name, t.Name)
+			}
+			rest = append(rest, args[i:]...)
+			return
+		case !ok:
+			return nil, nil, t.unknownFlagError(arg)
+		case f.Value == `` && hasVal:
+			return nil, nil, fmt.Errorf("[ERROR] `--%s` flag does not take a value.", name)
+		case f.Value != `` && !hasVal:
+			if i == argsLen-1 || strings.HasPrefix(args[i+1], `--`) {
+				return nil, nil, fmt.Errorf("[ERROR] `--%s` flag requires a %s value.", name, f.Value)
+			}
+			i++
+			val = args[i]
+		}
+
+		if _, given := flags[name]; given && !f.Repeated {
+			return nil, nil, fmt.Errorf("[ERROR] `--%s` flag given more than once.", name)
+		}
+		flags[name] = append(flags[name], val)
+	}
+
+	return
+}
+
+func (t *Command) flag(name string) (Flag, bool) {
+	for _, f := range t.Flags {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// suggestFlags returns the command's flags similar to a mistyped flag name.
+func (t *Command) suggestFlags(name string) (flags []string) {
+	names := make([]string, len(t.Flags))
+	for i, f := range t.Flags {
+		names[i] = f.Name
+	}
+	for _, n := range suggest(name, names) {
+		flags = append(flags, `--`+n)
+	}
+	return
+}
+
+func (t *Command) unknownFlagError(arg string) error {
+	if f, ok := globalFlag(arg); ok {
+		return fmt.Errorf("[ERROR] `--%s` is a global option; give it before the command, e.g. `%s --%s %s`.",
+			f.Name, env.AppName, f.Name, t.Name)
+	}
+	if len(t.Flags) == 0 {
+		return fmt.Errorf("[ERROR] unknown flag `%s`; `%s` takes no flags.", arg, t.Name)
+	}
+
+	valid := make([]string, len(t.Flags))
+	for i, f := range t.Flags {
+		valid[i] = f.String()
+	}
+	return fmt.Errorf("[ERROR] unknown flag `%s`; `%s` takes %s.", arg, t.Name, strings.Join(valid, `, `))
+}
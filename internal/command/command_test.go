@@ -2,6 +2,7 @@ package command
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"bitbucket.org/jonforums/uru/internal/env"
@@ -24,7 +25,7 @@ func TestCommandInit(t *testing.T) {
 		Short:    s,
 		Long:     l,
 		IsPlugin: p,
-		Run:      func(ctx *env.Context) {},
+		Run:      func(ctx *env.Context) error { return nil },
 	}
 
 	if !reflect.DeepEqual(a, cmd.Aliases) {
@@ -58,3 +59,64 @@ func TestCommandInit(t *testing.T) {
 		}
 	}
 }
+
+func TestCommandParseFlags(t *testing.T) {
+	cmd := &Command{
+		Name: "fake",
+		Flags: []Flag{
+			{Name: `verbose`},
+			{Name: `tag`, Value: `TAG`},
+			{Name: `only`, Value: `TAGS`, Repeated: true},
+		},
+	}
+
+	tests := []struct {
+		args  []string
+		flags map[string][]string
+		rest  []string
+	}{
+		{[]string{`dir`}, map[string][]string{}, []string{`dir`}},
+		{[]string{`--verbose`, `dir`, `--tag`, `foo`},
+			map[string][]string{`verbose`: {``}, `tag`: {`foo`}}, []string{`dir`}},
+		{[]string{`--tag=foo`, `--only`, `223`, `--only=231`},
+			map[string][]string{`tag`: {`foo`}, `only`: {`223`, `231`}}, nil},
+		{[]string{`dir`, `--`, `--verbose`, `--bogus`},
+			map[string][]string{}, []string{`dir`, `--verbose`, `--bogus`}},
+	}
+
+	for _, tt := range tests {
+		flags, rest, err := cmd.parseFlags(tt.args)
+		if err != nil {
+			t.Errorf("Command.parseFlags() returned error for `%v`: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(flags, tt.flags) {
+			t.Errorf("Command.parseFlags() not returning correct flags\n  want: `%v`\n  got: `%v`\n",
+				tt.flags,
+				flags)
+		}
+		if !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("Command.parseFlags() not returning correct args\n  want: `%v`\n  got: `%v`\n",
+				tt.rest,
+				rest)
+		}
+	}
+
+	invalid := [][]string{
+		{`--bogus`},
+		{`--verbose=yes`},
+		{`--tag`},
+		{`--tag`, `--verbose`},
+		{`--tag`, `foo`, `--tag`, `bar`},
+	}
+	for _, args := range invalid {
+		if _, _, err := cmd.parseFlags(args); err == nil {
+			t.Errorf("Command.parseFlags() not returning error for `%v`", args)
+		}
+	}
+
+	_, _, err := cmd.parseFlags([]string{`--json`})
+	if err == nil || !strings.Contains(err.Error(), "global option") {
+		t.Errorf("Command.parseFlags() not identifying a misplaced global option\n  got: `%v`", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var completeCmd *Command = &Command{
+	Name:    "__complete",
+	Aliases: []string{"__complete"},
+	Usage:   "__complete [--cword N] WORD...",
+	Eg:      "__complete admin r",
+	Short:   "list completion candidates for shell completion scripts",
+	Long: `Lists the completion candidates for the last of the given command line WORDs,
+one per line as a candidate, a tab and a description. The shell completion
+scripts written by 'uru admin completion' call it to complete commands, flags,
+registered ruby tags and gemset names. The --cword option gives the index of
+the word being completed when it isn't the last word.`,
+	Hidden: true,
+	Run:    complete,
+}
+
+func init() {
+	CmdRouter.Handle(completeCmd.Aliases, completeCmd)
+}
+
+// completion is a single shell completion candidate and its description.
+type completion struct {
+	word string
+	desc string
+}
+
+// Implements the hidden command
+//
+//    uru_rt __complete [--cword N] WORD...
+//
+// used by the scripts generated by `uru admin completion` to list completion
+// candidates, one `WORD<TAB>DESCRIPTION` line per candidate, for the words
+// typed after `uru`. The last word is the, possibly empty, word being
+// completed. As some shells drop empty args when running external commands,
+// `--cword N` gives the index of the word being completed; the word is empty if
+// N equals the number of words.
+func complete(ctx *env.Context) error {
+	words := ctx.CmdArgs()
+	if len(words) > 1 && words[0] == `--cword` {
+		n, err := strconv.Atoi(words[1])
+		words = words[2:]
+		if err == nil && n == len(words) {
+			words = append(words, ``)
+		}
+	}
+	if len(words) == 0 {
+		words = []string{``}
+	}
+
+	for _, c := range completions(ctx, words) {
+		fmt.Fprintf(ctx.Stdout, "%s\t%s\n", c.word, c.desc)
+	}
+
+	return nil
+}
+
+// completions returns the candidates, sorted and matching the word being
+// completed, for the last of the given words.
+func completions(ctx *env.Context, words []string) (cands []completion) {
+	cur, prev := words[len(words)-1], skipGlobalFlags(words[:len(words)-1])
+
+	switch {
+	case strings.HasPrefix(cur, `-`) && len(prev) == 0:
+		for _, f := range GlobalFlags {
+			cands = append(cands, completion{fmt.Sprintf("--%s", f.Name), f.Usage})
+		}
+	case strings.HasPrefix(cur, `-`):
+		cands = flagCompletions(prev)
+	case len(prev) == 0:
+		cands = append(commandCompletions(CmdRouter), tagCompletions(ctx)...)
+		cands = append(cands, completion{`auto`, "use the ruby named by .ruby-version"},
+			completion{`nil`, "remove uru rubies from the environment"})
+		for _, p := range discoverPlugins(ctx) {
+			cands = append(cands, completion{p.Name, p.Short})
+		}
+		for _, a := range aliasCommands(ctx) {
+			cands = append(cands, completion{a.Name, a.Short})
+		}
+		if strings.Contains(cur, `@`) {
+			cands = gemsetCompletions(ctx, cur)
+		}
+	default:
+		cands = argCompletions(ctx, prev, cur)
+	}
+
+	var matches []completion
+	for _, c := range cands {
+		if strings.HasPrefix(c.word, cur) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].word < matches[j].word })
+
+	return matches
+}
+
+// skipGlobalFlags returns the words following the global options, and their
+// values, given before the command.
+func skipGlobalFlags(words []string) []string {
+	for len(words) > 0 {
+		f, ok := globalFlag(words[0])
+		if !ok {
+			break
+		}
+		if f.Value != `` && !strings.Contains(words[0], `=`) && len(words) > 1 {
+			words = words[1:]
+		}
+		words = words[1:]
+	}
+	return words
+}
+
+// argCompletions returns the candidates for the args of the command, or
+// sub-command, named by the leading words.
+func argCompletions(ctx *env.Context, prev []string, cur string) []completion {
+	cmd, n := CmdRouter.Walk(prev)
+	if cmd == nil {
+		return nil
+	}
+	args := prev[n:]
+
+	if cmd.Subcommands != nil {
+		if len(args) == 0 {
+			return commandCompletions(cmd.Subcommands)
+		}
+		return nil
+	}
+
+	if len(args) > 0 && args[len(args)-1] == `--format` {
+		if cmd == adminDocsCmd {
+			return []completion{{`man`, "man page per command"}, {`markdown`, "markdown command reference"}}
+		}
+		return []completion{{`json`, "versioned JSON document"}, {`tsv`, "tab separated values"}}
+	}
+
+	switch cmd {
+	case helpCmd:
+		if len(args) == 0 {
+			return commandCompletions(CmdRouter)
+		}
+		if c, m := CmdRouter.Walk(args); m == len(args) && c.Subcommands != nil {
+			return commandCompletions(c.Subcommands)
+		}
+	case execCmd:
+		if len(args) == 0 {
+			return append(tagCompletions(ctx), completion{`auto`, "use the ruby named by .ruby-version"})
+		}
+	case adminRemoveCmd, adminRetagCmd:
+		if len(args) == 0 {
+			return tagCompletions(ctx)
+		}
+	case gemsetInitCmd, gemsetInfoCmd, gemsetRmCmd:
+		return gemsetCompletions(ctx, cur)
+	case gemsMigrateCmd:
+		return append(tagCompletions(ctx), gemsetCompletions(ctx, cur)...)
+	case adminCompletionCmd:
+		if len(args) == 0 {
+			cands := []completion{}
+			for _, sh := range completionShells {
+				cands = append(cands, completion{sh, fmt.Sprintf("%s completion script", sh)})
+			}
+			return cands
+		}
+	}
+
+	return nil
+}
+
+// commandCompletions returns the aliases of the visible commands registered
+// with a router.
+func commandCompletions(r *Router) (cands []completion) {
+	for alias, c := range *r.Handlers() {
+		if !c.Hidden {
+			cands = append(cands, completion{alias, c.Short})
+		}
+	}
+	return
+}
+
+// flagCompletions returns the flags of the command, or sub-command, named by
+// the words.
+func flagCompletions(prev []string) (cands []completion) {
+	c, _ := CmdRouter.Walk(prev)
+	if c == nil {
+		return nil
+	}
+
+	for _, f := range c.Flags {
+		cands = append(cands, completion{fmt.Sprintf("--%s", f.Name), f.Usage})
+	}
+
+	return
+}
+
+// tagCompletions returns the tag labels of the registered rubies.
+func tagCompletions(ctx *env.Context) (cands []completion) {
+	for _, ri := range ctx.Registry.Rubies {
+		cands = append(cands, completion{ri.TagLabel, ri.Description})
+	}
+	return
+}
+
+// gemsetCompletions returns the TAG@gemset project gemset and TAG@NAME named
+// gemset names for each registered ruby. If the word being completed already
+// contains a `@`, its ruby part is kept as typed since uru also accepts partial
+// tag labels, e.g. `32@rails7`.
+func gemsetCompletions(ctx *env.Context, cur string) (cands []completion) {
+	names := []string{`gemset`}
+	if entries, err := ioutil.ReadDir(filepath.Join(ctx.Home(), `gemsets`)); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+
+	var rubies []string
+	if i := strings.Index(cur, `@`); i >= 0 {
+		rubies = []string{cur[:i]}
+	} else {
+		for _, ri := range ctx.Registry.Rubies {
+			rubies = append(rubies, ri.TagLabel)
+		}
+	}
+
+	for _, r := range rubies {
+		for _, n := range names {
+			desc := "named gemset"
+			if n == `gemset` {
+				desc = "project gemset"
+			}
+			cands = append(cands, completion{fmt.Sprintf("%s@%s", r, n), desc})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,53 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestCompletions(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(`no-such-uru-home`)
+	ctx.Registry.Rubies = env.RubyMap{
+		`3574260220`: env.Ruby{TagLabel: `223p146`},
+		`1234567890`: env.Ruby{TagLabel: `231`},
+	}
+
+	var tests = []struct {
+		Words []string
+		Want  []string
+	}{
+		{[]string{`22`}, []string{`223p146`}},
+		{[]string{`au`}, []string{`auto`}},
+		{[]string{`admin`, `re`}, []string{`refresh`, `retag`}},
+		{[]string{`admin`, `rm`, `2`}, []string{`223p146`, `231`}},
+		{[]string{`admin`, `gemset`, `init`, `23@`}, []string{`23@gemset`}},
+		{[]string{`exec`, `23`}, []string{`231`}},
+		{[]string{`ls`, `--v`}, []string{`--verbose`}},
+		{[]string{`admin`, `completion`, `z`}, []string{`zsh`}},
+		{[]string{`admin`, `gs`, `i`}, []string{`info`, `init`}},
+		{[]string{`admin`, `gems`, `migrate`, `22`}, []string{`223p146`, `223p146@gemset`}},
+		{[]string{`help`, `admin`, `gemset`, `r`}, []string{`rm`}},
+		{[]string{`__comp`}, nil},
+		{[]string{`--n`}, []string{`--no-color`, `--no-input`}},
+		{[]string{`ls`, `--format`, ``}, []string{`json`, `tsv`}},
+		{[]string{`--home`, `/tmp/uru`, `--yes`, `admin`, `rm`, `23`}, []string{`231`}},
+		{[]string{`--log-level=debug`, `ls`, `--v`}, []string{`--verbose`}},
+	}
+
+	for _, v := range tests {
+		var got []string
+		for _, c := range completions(ctx, v.Words) {
+			got = append(got, c.word)
+		}
+		if !reflect.DeepEqual(got, v.Want) {
+			t.Errorf("completions() not returning correct value for `%v`\n  want: `%v`\n  got: `%v`",
+				v.Words, v.Want, got)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
+)
+
+// Exit code returned when the command given to `exec` cannot be found on the
+// PATH of the selected ruby. Matches the convention used by POSIX shells.
+const execNotFoundExitCode = 127
+
+var execCmd *Command = &Command{
+	Name:    "exec",
+	Aliases: []string{"exec"},
+	Usage:   "exec TAG|auto -- CMD ARGS...",
+	Eg:      "exec 223p146 -- rake test",
+	Short:   "run a command with the ruby identified by TAG",
+	Long: `Runs a single command in a child process whose PATH and GEM_HOME are those
+of the ruby identified by TAG, or with 'auto' of the ruby named by the nearest
+.ruby-version file. The calling shell's environment isn't changed, so exec
+works without the uru shell wrapper, e.g. from cron jobs and IDE tasks.
+
+Uru exits with the command's exit code, or 127 if the command isn't found on
+the ruby's PATH. Interrupt and termination signals are forwarded to the
+command.`,
+	Run: execRuby,
+}
+
+func init() {
+	CmdRouter.Handle(execCmd.Aliases, execCmd)
+}
+
+// Implements the functionality for the user visible command
+//
+//    uru exec TAG|auto -- CMD ARGS...
+//
+// which runs a single command in a child process whose PATH and GEM_HOME are
+// those of the ruby identified by TAG. The calling shell's environment is
+// never modified, so `exec` works when invoking uru_rt directly from cron jobs,
+// IDE tasks, and other environments without the uru shell wrapper. The exit
+// code of the child process becomes uru's exit code.
+func execRuby(ctx *env.Context) error {
+	label, cmdArgs, err := parseExecArgs(ctx.CmdArgs())
+	if err != nil {
+		return err
+	}
+
+	tagHash, err := execTagHash(ctx, label)
+	if err != nil {
+		return err
+	}
+
+	return exitWith(execWithRuby(ctx, tagHash, cmdArgs[0], cmdArgs[1:]))
+}
+
+// parseExecArgs splits `exec` command arguments of the form
+//
+//    TAG [--] CMD ARGS...
+//
+// into the ruby tag label and the command line to run.
+func parseExecArgs(args []string) (label string, cmdArgs []string, err error) {
+	if len(args) < 2 {
+		return ``, nil, errors.New("[ERROR] invalid `exec TAG -- CMD ARGS...` invocation.")
+	}
+
+	label, cmdArgs = args[0], args[1:]
+	if cmdArgs[0] == `--` {
+		cmdArgs = cmdArgs[1:]
+	}
+	if len(cmdArgs) == 0 {
+		return ``, nil, errors.New("[ERROR] must specify the command to run with `exec`.")
+	}
+
+	return
+}
+
+// execTagHash returns the tag hash of the single registered ruby identified by
+// the user specified label. The `auto` label selects the ruby named by the
+// nearest `.ruby-version` file.
+func execTagHash(ctx *env.Context, label string) (tagHash string, err error) {
+	var tags env.RubyMap
+
+	switch label {
+	case `auto`:
+		// searching for a .ruby-version file changes the working directory so
+		// restore it before running the user's command.
+		cwd, e := os.Getwd()
+		if e != nil {
+			return ``, errors.New("---> unable to determine current working dir")
+		}
+		tags, err = useRubyVersionFile(ctx, versionator)
+		os.Chdir(cwd)
+		if err != nil {
+			return ``, errors.New("---> unable to find or process a `.ruby-version` file")
+		}
+	case `nil`:
+		return ``, errors.New("---> `exec` requires a registered ruby, not `nil`")
+	default:
+		tags, err = env.VersionFragmentToTag(ctx, label)
+		if err != nil {
+			return ``, noRubyError(ctx, label)
+		}
+	}
+
+	if len(tags) == 1 {
+		for t := range tags {
+			tagHash = t
+		}
+		return
+	}
+
+	// multiple rubies match the given tag label, ask the user for the
+	// correct one.
+	if tagHash, err = env.SelectRubyFromList(ctx, tags, label, `exec`); err != nil {
+		return ``, selectionError(err)
+	}
+	return
+}
+
+// execWithRuby runs the command in a child process using the PATH and GEM_HOME
+// of the ruby identified by the tag hash and returns the child's exit code.
+// Interrupt and termination signals received by uru while the child runs are
+// forwarded to the child rather than terminating uru.
+func execWithRuby(ctx *env.Context, tagHash, cmd string, cmdArgs []string) int {
+	info := ctx.Registry.Rubies[tagHash]
+
+	environ, pth, err := rubyEnviron(ctx, tagHash)
+	if err != nil {
+		fmt.Fprintf(ctx.Stdout, "---> unable to use ruby internally known as `%s`\n", tagHash)
+		return 1
+	}
+
+	// resolve the command using the new PATH
+	exe, err := findExecutable(cmd, pth)
+	if err != nil {
+		fmt.Fprintf(ctx.Stdout, "---> unable to find `%s` for ruby tagged as `%s`\n", cmd, info.TagLabel)
+		return execNotFoundExitCode
+	}
+	runner := exec.Command(exe, cmdArgs...)
+	runner.Env = environ
+	runner.Stdin = ctx.Stdin
+	runner.Stdout = ctx.Stdout
+	runner.Stderr = ctx.Stderr
+
+	return runForwardingSignals(ctx, runner, fmt.Sprintf("%s %s", cmd, strings.Join(cmdArgs, " ")))
+}
+
+// runForwardingSignals runs the configured command, forwarding interrupt and
+// termination signals received by uru to the child process rather than
+// terminating uru, and returns the child's exit code.
+func runForwardingSignals(ctx *env.Context, runner *exec.Cmd, cmdLine string) int {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	log.Debug("spawn", "exe", runner.Path, "args", runner.Args[1:])
+	start := time.Now()
+	if err := runner.Start(); err != nil {
+		fmt.Fprintf(ctx.Stdout, "---> unable to run `%s`\n", cmdLine)
+		log.Warn("spawn failed", "exe", runner.Path, "err", err)
+		return 1
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case s := <-sigs:
+				// windows only supports os.Kill; a console Ctrl-C is already
+				// delivered to every process attached to the console.
+				log.Debug("forwarding signal", "signal", s, "pid", runner.Process.Pid)
+				runner.Process.Signal(s)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := runner.Wait()
+	log.Debug("exit", "exe", runner.Path, "pid", runner.Process.Pid,
+		"code", runner.ProcessState.ExitCode(), "duration", time.Since(start))
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if code := exitErr.ExitCode(); code >= 0 {
+				return code
+			}
+		}
+		// terminated by a signal or otherwise failed without an exit code
+		log.Warn("wait failed", "exe", runner.Path, "err", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,46 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testExecArgs = []struct {
+	Args    []string
+	Label   string
+	CmdArgs []string
+	Valid   bool
+}{
+	{[]string{`223`, `--`, `rake`, `test`}, `223`, []string{`rake`, `test`}, true},
+	{[]string{`auto`, `rake`, `test`}, `auto`, []string{`rake`, `test`}, true},
+	{[]string{`223`, `--`, `ruby`, `--`, `-e`}, `223`, []string{`ruby`, `--`, `-e`}, true},
+	{[]string{`223`, `--`}, ``, nil, false},
+	{[]string{`223`}, ``, nil, false},
+	{[]string{}, ``, nil, false},
+}
+
+func TestParseExecArgs(t *testing.T) {
+	for _, v := range testExecArgs {
+		label, cmdArgs, err := parseExecArgs(v.Args)
+		if (err == nil) != v.Valid {
+			t.Errorf("parseExecArgs() incorrect error return for `%v`\n  want valid: `%v`\n  got: `%v`",
+				v.Args,
+				v.Valid,
+				err)
+			continue
+		}
+		if label != v.Label {
+			t.Errorf("parseExecArgs() returning incorrect label value\n  want: `%v`\n  got: `%v`",
+				v.Label,
+				label)
+		}
+		if !reflect.DeepEqual(cmdArgs, v.CmdArgs) {
+			t.Errorf("parseExecArgs() returning incorrect command value\n  want: `%v`\n  got: `%v`",
+				v.CmdArgs,
+				cmdArgs)
+		}
+	}
+}
@@ -10,18 +10,32 @@ import (
 var gemCmd *Command = &Command{
 	Name:    "gem",
 	Aliases: []string{"gem"},
-	Usage:   "gem ARGS...",
+	Usage:   "gem [SELECT_OPTS] [--jobs N] [--fail-fast] [--report FMT=PATH] ARGS...",
 	Eg:      "gem install narray",
-	Short:   "run a gem command with all registered rubies",
-	Run:     gem,
+	Short:   "run a gem command with registered rubies",
+	Long: `Runs a gem command with each registered ruby in turn, displaying each ruby's
+description before its output. SELECT_OPTS, the --only, --except, --engine,
+--ruby-version and --order flags, limit and order the rubies to run. Uru's
+flags end at -- or at the first arg not naming one of them, so gem's own
+flags such as --version are passed to gem.
+
+Uru exits with 2 if the gem command failed with any ruby, or 3 if it couldn't
+be started with any ruby. The --fail-fast option stops after the first
+failing ruby, --jobs runs up to N rubies concurrently, and --report writes a
+junit or json report of the results to PATH.`,
+	Flags: multiRubyFlags(
+		Flag{Name: `jobs`, Value: `N`, Usage: "run up to N rubies concurrently, 1 by default"},
+		Flag{Name: `fail-fast`, Usage: "stop running rubies after the first failure"},
+		Flag{Name: `report`, Value: `FMT=PATH`, Repeated: true, Usage: "write a junit or json report of the results to PATH"},
+	),
+	ProgramArgs: true,
+	Run:         gem,
 }
 
 func init() {
 	CmdRouter.Handle(gemCmd.Aliases, gemCmd)
 }
 
-func gem(ctx *env.Context) {
-	if err := rubyExec(ctx); err != nil {
-		// TODO implement me
-	}
+func gem(ctx *env.Context) error {
+	return multiRubyExec(ctx)
 }
@@ -6,7 +6,6 @@ package command
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 
@@ -16,33 +15,40 @@ import (
 var helpCmd *Command = &Command{
 	Name:    "help",
 	Aliases: []string{"help"},
-	Usage:   "help",
-	Eg:      "help",
-	Short:   "help",
-	Run:     help,
+	Usage:   "help [CMD [SUBCMD]]",
+	Eg:      "help admin gemset",
+	Short:   "display uru help or the help of a command",
+	Long: `Without a command, lists uru's commands, plugins, aliases and global options.
+Given a command, or a command and its sub-commands, displays the command's
+usage, flags and description. For plugins, try 'uru NAME --help'.`,
+	Run: help,
 }
 
 func init() {
 	CmdRouter.Handle(helpCmd.Aliases, helpCmd)
 }
 
-func help(ctx *env.Context) {
+func help(ctx *env.Context) error {
 	cmdArgs := ctx.CmdArgs()
 	if len(cmdArgs) == 0 {
-		fmt.Fprintf(os.Stderr, "%s v%s\n", env.AppName, env.AppVersion)
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] CMD ARG...\n", env.AppName)
-		fmt.Fprintln(os.Stderr, "\nwhere CMD is one of:")
-		printCommandSummary()
-		fmt.Fprintf(os.Stderr, "\nfor help on a particular command, type `%s help CMD`\n",
+		fmt.Fprintf(ctx.Stderr, "%s v%s\n", env.AppName, env.AppVersion)
+		fmt.Fprintf(ctx.Stderr, "Usage: %s [options] CMD ARG...\n", env.AppName)
+		fmt.Fprintln(ctx.Stderr, "\nwhere CMD is one of:")
+		printCommandSummary(ctx)
+		printPluginSummary(ctx)
+		printAliasSummary(ctx)
+		fmt.Fprintln(ctx.Stderr, "\nand options are:")
+		printFlagsSummary(ctx, GlobalFlags, 2)
+		fmt.Fprintf(ctx.Stderr, "\nfor help on a particular command, type `%s help CMD`\n",
 			env.AppName)
 	} else {
-		commandHelp(cmdArgs[0])
+		commandHelp(ctx, cmdArgs)
 	}
 
-	os.Exit(0)
+	return nil
 }
 
-func printCommandSummary() {
+func printCommandSummary(ctx *env.Context) {
 	keys, cmds := []string{}, *CmdRouter.Commands()
 
 	for k := range cmds {
@@ -51,33 +57,79 @@ func printCommandSummary() {
 	sort.Strings(keys)
 
 	for _, v := range keys {
-		fmt.Fprintf(os.Stderr, "%6.6s   %s\n", v, cmds[v].Short)
+		fmt.Fprintf(ctx.Stderr, "%6.6s   %s\n", v, cmds[v].Short)
 	}
 }
 
-func printAdminCommandSummary() {
-	keys, cmds := []string{}, *adminRouter.Commands()
+func printSubcommandSummary(ctx *env.Context, cmd *Command) {
+	keys, cmds := []string{}, *cmd.Subcommands.Commands()
 
 	for k := range cmds {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	fmt.Fprintln(os.Stderr, "\nwhere SUBCMD is one of:")
+	fmt.Fprintln(ctx.Stderr, "\nwhere SUBCMD is one of:")
 	for _, v := range keys {
-		fmt.Fprintf(os.Stderr, "%8.8s   %s\n", v, cmds[v].Short)
+		fmt.Fprintf(ctx.Stderr, "%8.8s   %s\n", v, cmds[v].Short)
 		if cmds[v].Aliases != nil {
-			fmt.Fprintf(os.Stderr, "%8.8s   aliases: %s\n", "", strings.Join(cmds[v].Aliases, ", "))
+			fmt.Fprintf(ctx.Stderr, "%8.8s   aliases: %s\n", "", strings.Join(cmds[v].Aliases, ", "))
 		}
-		fmt.Fprintf(os.Stderr, "%8.8s   usage: %s %s\n", "", env.AppName, cmds[v].Usage)
-		fmt.Fprintf(os.Stderr, "%8.8s   eg: %s %s\n\n", "", env.AppName, cmds[v].Eg)
+		fmt.Fprintf(ctx.Stderr, "%8.8s   usage: %s %s\n", "", env.AppName, cmds[v].Usage)
+		fmt.Fprintf(ctx.Stderr, "%8.8s   eg: %s %s\n", "", env.AppName, cmds[v].Eg)
+		if len(cmds[v].Flags) > 0 {
+			fmt.Fprintf(ctx.Stderr, "%8.8s   flags:\n", "")
+			printFlagsSummary(ctx, cmds[v].Flags, 13)
+		}
+		fmt.Fprintln(ctx.Stderr)
+	}
+}
+
+func printPluginSummary(ctx *env.Context) {
+	plugins := discoverPlugins(ctx)
+	if len(plugins) == 0 {
+		return
+	}
+
+	fmt.Fprintln(ctx.Stderr, "\nor one of the following plugin commands:")
+	for _, p := range plugins {
+		fmt.Fprintf(ctx.Stderr, "%6.6s   %s\n", p.Name, p.Short)
+	}
+}
+
+func printAliasSummary(ctx *env.Context) {
+	aliases := aliasCommands(ctx)
+	if len(aliases) == 0 {
+		return
+	}
+
+	fmt.Fprintln(ctx.Stderr, "\nor one of the following aliases:")
+	for _, a := range aliases {
+		fmt.Fprintf(ctx.Stderr, "%6.6s   %s\n", a.Name, a.Short)
 	}
 }
 
-func commandHelp(cmd string) {
-	command, err := CmdRouter.Handler(cmd)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "---> No help available on `%s`\n", cmd)
+// commandHelp displays the help of the command, or sub-command, named by the
+// path of command names, e.g. `admin gemset init`.
+func commandHelp(ctx *env.Context, path []string) {
+	cmd := path[0]
+	if _, err := CmdRouter.Handler(cmd); err != nil {
+		if def, ok := ctx.Config.Get(aliasSection, cmd); ok {
+			fmt.Fprintf(ctx.Stderr, "  Alias: %s = %s\n", cmd, def)
+			return
+		}
+		if exe, ok := findPlugin(ctx, cmd); ok {
+			fmt.Fprintf(ctx.Stderr, "  Description: %s\n  Plugin: %s\n", pluginShort(exe), exe)
+			fmt.Fprintf(ctx.Stderr, "\nfor plugin usage, try `%s %s --help`\n", env.AppName, cmd)
+			return
+		}
+		fmt.Fprintf(ctx.Stderr, "---> No help available on `%s`\n", cmd)
+		return
+	}
+
+	command, n := CmdRouter.Walk(path)
+	if n != len(path) {
+		fmt.Fprintf(ctx.Stderr, "---> No help available on `%s`\n", strings.Join(path, ` `))
 		return
 	}
 
@@ -87,13 +139,51 @@ func commandHelp(cmd string) {
 	}
 	buf.WriteString("  Usage: %s %s\n  Example: %s %s\n")
 
-	fmt.Fprintf(os.Stderr,
+	fmt.Fprintf(ctx.Stderr,
 		buf.String(),
 		command.Short,
 		env.AppName, command.Usage,
 		env.AppName, command.Eg)
 
-	if cmd == `admin` {
-		printAdminCommandSummary()
+	if len(command.Flags) > 0 {
+		fmt.Fprintln(ctx.Stderr, "  Flags:")
+		printFlagsSummary(ctx, command.Flags, 4)
+	}
+	printLongDescription(ctx, command.Long)
+	if command.Subcommands != nil {
+		printSubcommandSummary(ctx, command)
+	}
+}
+
+// printLongDescription displays a command's Long description indented below
+// the command's summary.
+func printLongDescription(ctx *env.Context, long string) {
+	if long == `` {
+		return
+	}
+	fmt.Fprintln(ctx.Stderr)
+	for _, l := range strings.Split(strings.TrimSpace(long), "\n") {
+		if l == `` {
+			fmt.Fprintln(ctx.Stderr)
+			continue
+		}
+		fmt.Fprintf(ctx.Stderr, "  %s\n", l)
+	}
+}
+
+func printFlagsSummary(ctx *env.Context, flags []Flag, indent int) {
+	width := 0
+	for _, f := range flags {
+		if n := len(f.String()); n > width {
+			width = n
+		}
+	}
+
+	for _, f := range flags {
+		usage := f.Usage
+		if f.Repeated {
+			usage += " (repeatable)"
+		}
+		fmt.Fprintf(ctx.Stderr, "%*s%-*s   %s\n", indent, "", width, f.String(), usage)
 	}
 }
@@ -5,7 +5,6 @@ package command
 
 import (
 	"fmt"
-	"os"
 
 	"bitbucket.org/jonforums/uru/internal/env"
 )
@@ -13,10 +12,21 @@ import (
 var listCmd *Command = &Command{
 	Name:    "ls",
 	Aliases: []string{"ls", "list"},
-	Usage:   "ls [--verbose]",
+	Usage:   "ls [--verbose] [--format FORMAT]",
 	Eg:      "ls",
 	Short:   "list all registered ruby installations",
-	Run:     list,
+	Long: `Lists the rubies registered with uru by tag label and description, marking
+the active ruby, and gemset if any, with '=>'. The --verbose option also
+displays each ruby's ID, home and gem home.
+
+The --format option displays full, untruncated results for scripts: a
+versioned JSON document, tab separated values, or a Go text/template applied
+to each ruby.`,
+	Flags: []Flag{
+		{Name: `verbose`, Usage: "also display each ruby's ID, home and gem home"},
+		formatFlag,
+	},
+	Run: list,
 }
 
 func init() {
@@ -24,30 +34,39 @@ func init() {
 }
 
 // List all rubies registered with uru, identifying the currently active ruby
-func list(ctx *env.Context) {
-	if len(ctx.Registry.Rubies) == 0 {
-		fmt.Println("---> No rubies registered with uru")
-		return
+// and gemset
+func list(ctx *env.Context) error {
+	format, err := parseOutputFormat(ctx)
+	if err != nil {
+		return err
 	}
 
-	verbose := false
-	for _, v := range ctx.CmdArgs() {
-		if v == `--verbose` {
-			verbose = true
-			break
+	if len(ctx.Registry.Rubies) == 0 {
+		if !format.isDefault() {
+			return format.write(ctx, `rubies`, []rubyRecord{})
 		}
+		fmt.Fprintln(ctx.Stdout, "---> No rubies registered with uru")
+		return nil
 	}
 
+	verbose := ctx.IsFlagSet(`verbose`)
+
 	tagHash, _, err := env.CurrentRubyInfo(ctx)
 	if err != nil {
-		fmt.Printf("---> unable to list rubies; try again (%s)\n", err)
-		os.Exit(1)
+		return fmt.Errorf("---> unable to list rubies; try again (%s)", err)
 	}
 
 	sortedTagHashes, err := env.SortTagsByTagLabel(&ctx.Registry.Rubies)
 	if err != nil {
-		fmt.Printf("---> unable to list sorted rubies; try again (%s)\n", err)
-		os.Exit(1)
+		return fmt.Errorf("---> unable to list sorted rubies; try again (%s)", err)
+	}
+
+	if !format.isDefault() {
+		rubies := make([]rubyRecord, 0, len(sortedTagHashes))
+		for _, t := range sortedTagHashes {
+			rubies = append(rubies, newRubyRecord(ctx, t, t == tagHash))
+		}
+		return format.write(ctx, `rubies`, rubies)
 	}
 
 	var me, desc string
@@ -66,10 +85,60 @@ func list(ctx *env.Context) {
 			desc = fmt.Sprintf("%.64s...", desc)
 		}
 
-		fmt.Printf(" %s %-12.12s: %s\n", me, ri.TagLabel, desc)
+		fmt.Fprintf(ctx.Stdout, " %s %-12.12s: %s\n", me, ri.TagLabel, desc)
+		if t == tagHash {
+			switch gemset, dir := activeGemset(ctx, ri); gemset {
+			case ``:
+			case `gemset`:
+				fmt.Fprintf(ctx.Stdout, "%s gemset: project (%s)\n", indent, dir)
+			default:
+				fmt.Fprintf(ctx.Stdout, "%s gemset: %s (%s)\n", indent, gemset, dir)
+			}
+		}
 		if verbose {
-			fmt.Printf("%s ID: %s\n%s Home: %s\n%s GemHome: %s\n\n",
+			fmt.Fprintf(ctx.Stdout, "%s ID: %s\n%s Home: %s\n%s GemHome: %s\n\n",
 				indent, ri.ID, indent, ri.Home, indent, ri.GemHome)
 		}
 	}
+
+	return nil
+}
+
+// rubyRecord is the machine readable form of a registered ruby displayed by
+// `ls --format`. Its fields are part of uru's documented output schema.
+type rubyRecord struct {
+	TagLabel    string `json:"tag_label"`
+	TagHash     string `json:"tag_hash"`
+	Current     bool   `json:"current"`
+	ID          string `json:"id"`
+	Exe         string `json:"exe"`
+	Home        string `json:"home"`
+	GemHome     string `json:"gem_home"`
+	Gemset      string `json:"gemset"`
+	GemsetDir   string `json:"gemset_dir"`
+	Description string `json:"description"`
+}
+
+// newRubyRecord returns the record of a registered ruby. Only the current
+// ruby has an active gemset, either `project` or a named gemset.
+func newRubyRecord(ctx *env.Context, tagHash string, current bool) rubyRecord {
+	ri := ctx.Registry.Rubies[tagHash]
+	r := rubyRecord{
+		TagLabel:    ri.TagLabel,
+		TagHash:     tagHash,
+		Current:     current,
+		ID:          ri.ID,
+		Exe:         ri.Exe,
+		Home:        ri.Home,
+		GemHome:     ri.GemHome,
+		Description: ri.Description,
+	}
+	if current {
+		r.Gemset, r.GemsetDir = activeGemset(ctx, ri)
+		if r.Gemset == `gemset` {
+			r.Gemset = `project`
+		}
+	}
+
+	return r
 }
@@ -0,0 +1,157 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var matrixCmd *Command = &Command{
+	Name:    "matrix",
+	Aliases: []string{"matrix"},
+	Usage:   "matrix [SELECT_OPTS] [--jobs N] [--fail-fast] [--logs DIR] [--report FMT=PATH] -- CMD ARGS...",
+	Eg:      "matrix --only 223,231 --jobs 2 -- rake test",
+	Short:   "run a command concurrently with registered rubies",
+	Long: `Runs a command concurrently with each registered ruby chosen by SELECT_OPTS,
+up to --jobs rubies at once, by default the number of CPUs. Each ruby's
+output is captured and displayed as a single block when that ruby finishes,
+followed by a pass/fail summary of all rubies. SELECT_OPTS are the --only,
+--except, --engine, --ruby-version and --order flags. Uru's flags end at --
+or at CMD.
+
+The --logs option also writes each ruby's output to a log file in DIR, and
+--report writes a junit or json report of the results to PATH. Uru exits
+with 2 if the command failed with any ruby, or 3 if it couldn't be started
+with any ruby.`,
+	Flags: multiRubyFlags(
+		Flag{Name: `jobs`, Value: `N`, Usage: "run up to N rubies concurrently, the number of CPUs by default"},
+		Flag{Name: `fail-fast`, Usage: "skip the rubies not yet started after the first failure"},
+		Flag{Name: `logs`, Value: `DIR`, Usage: "write each ruby's output to a log file in DIR"},
+		Flag{Name: `report`, Value: `FMT=PATH`, Repeated: true, Usage: "write a junit or json report of the results to PATH"},
+	),
+	ProgramArgs: true,
+	Run:         matrix,
+}
+
+func init() {
+	CmdRouter.Handle(matrixCmd.Aliases, matrixCmd)
+}
+
+type matrixOptions struct {
+	multiRubyOptions
+	logDir  string   // directory to write per-ruby logs, none if empty
+	cmdArgs []string // command line to run with each ruby
+}
+
+// Implements the functionality for the user visible command
+//
+//    uru matrix [SELECT_OPTS] [--jobs N] [--fail-fast] [--logs DIR] [--report FMT=PATH] -- CMD ARGS...
+//
+// which concurrently runs a command with each registered ruby chosen by the
+// `--only`, `--except`, `--engine`, `--ruby-version` and `--order` options
+// shared by all multi-ruby commands. Each ruby's combined stdout and stderr is
+// captured separately and displayed as a single block when that ruby finishes,
+// followed by a pass/fail summary of all rubies. Uru exits with the
+// ExitRubyFailed or ExitRubyError exit code if the command failed for any ruby.
+func matrix(ctx *env.Context) error {
+	opts, err := parseMatrixFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	tagHashes, err := selectRubies(ctx, &opts.selector)
+	if err != nil {
+		return err
+	}
+
+	if opts.logDir != `` {
+		if err = os.MkdirAll(opts.logDir, os.ModeDir|0750); err != nil {
+			return fmt.Errorf("[ERROR] unable to create log dir `%s`", opts.logDir)
+		}
+	}
+
+	ctx.SetCmdAndArgs(opts.cmdArgs[0], opts.cmdArgs[1:])
+
+	var mu sync.Mutex
+	results, _ := rubyExecConcurrent(ctx, tagHashes, opts.jobs, opts.failFast, func(r *rubyResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		printRubyResult(ctx, r)
+
+		if opts.logDir != `` {
+			logFile := filepath.Join(opts.logDir, fmt.Sprintf("%s.log", r.Ruby.TagLabel))
+			if e := ioutil.WriteFile(logFile, r.Output, 0640); e != nil {
+				fmt.Fprintf(ctx.Stdout, "---> unable to write log file `%s`\n", logFile)
+			}
+		}
+	})
+
+	cmdLine := strings.Join(opts.cmdArgs, " ")
+	printMatrixSummary(ctx, results, cmdLine)
+
+	if err = writeReports(opts.reports, cmdLine, results); err != nil {
+		return err
+	}
+	if e, ok := resultsError(results).(*multiRubyError); ok {
+		// the failed rubies are listed in the matrix summary
+		return exitStatus(e.ExitCode())
+	}
+
+	return nil
+}
+
+// parseMatrixFlags returns the `matrix` command options and the command line
+// to run, which starts after `--` or at the first arg not naming a flag.
+func parseMatrixFlags(ctx *env.Context) (opts matrixOptions, err error) {
+	if opts.multiRubyOptions, err = parseMultiRubyFlags(ctx, runtime.NumCPU()); err != nil {
+		return
+	}
+	opts.logDir, opts.cmdArgs = ctx.Flag(`logs`), ctx.CmdArgs()
+
+	switch {
+	case len(opts.cmdArgs) == 0:
+		return opts, errors.New("[ERROR] must specify the command to run with `matrix`.")
+	case strings.HasPrefix(opts.cmdArgs[0], `--`):
+		// a command line starting with an option is an unknown matrix flag
+		return opts, fmt.Errorf("[ERROR] unknown `matrix` flag `%s`\n---> see `%s help matrix` for the `matrix` flags",
+			opts.cmdArgs[0], env.AppName)
+	}
+
+	return
+}
+
+// printMatrixSummary displays a pass/fail table for all results.
+func printMatrixSummary(ctx *env.Context, results []*rubyResult, cmdLine string) {
+	fmt.Fprintf(ctx.Stdout, "\n---> matrix summary for `%s`\n\n", cmdLine)
+	fmt.Fprintf(ctx.Stdout, "  %-12.12s  %-6.6s  %10.10s\n", `TAG`, `STATUS`, `TIME`)
+	for _, r := range results {
+		note := ``
+		switch {
+		case r.Err != nil:
+			note = fmt.Sprintf("  (%s)", r.Err)
+		case r.ExitCode != 0:
+			note = fmt.Sprintf("  (exit %d)", r.ExitCode)
+		}
+
+		color := colorGreen
+		if !r.Passed() {
+			color = colorRed
+		}
+		status := colorize(ctx, color, fmt.Sprintf("%-6.6s", r.status()))
+
+		fmt.Fprintf(ctx.Stdout, "  %-12.12s  %s  %10v%s\n", r.Ruby.TagLabel, status,
+			r.Duration.Round(time.Millisecond), note)
+	}
+}
@@ -0,0 +1,75 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testMatrixArgs = []struct {
+	Args    []string
+	Only    []string
+	Jobs    int
+	LogDir  string
+	CmdArgs []string
+	Valid   bool
+}{
+	{[]string{`--`, `rake`, `test`}, nil, 0, ``, []string{`rake`, `test`}, true},
+	{[]string{`rake`, `--only`, `x`}, nil, 0, ``, []string{`rake`, `--only`, `x`}, true},
+	{[]string{`--only`, `223,231`, `--jobs`, `2`, `--`, `rake`}, []string{`223`, `231`}, 2, ``, []string{`rake`}, true},
+	{[]string{`--logs`, `tmp/logs`, `ruby`, `-v`}, nil, 0, `tmp/logs`, []string{`ruby`, `-v`}, true},
+	{[]string{`--jobs`, `0`, `--`, `rake`}, nil, 0, ``, nil, false},
+	{[]string{`--jobs`}, nil, 0, ``, nil, false},
+	{[]string{`--bogus`, `rake`}, nil, 0, ``, nil, false},
+	{[]string{`--only`, `223`, `--jbos`, `2`, `rake`}, nil, 0, ``, nil, false},
+	{[]string{`--only`, `223`, `--only`, `231`, `rake`}, []string{`223`, `231`}, 0, ``, []string{`rake`}, true},
+	{[]string{`--only`, `223`, `--`}, nil, 0, ``, nil, false},
+}
+
+func TestParseMatrixFlags(t *testing.T) {
+	for _, v := range testMatrixArgs {
+		var opts matrixOptions
+		ctx, err := flagsContext(matrixCmd, v.Args)
+		if err == nil {
+			opts, err = parseMatrixFlags(ctx)
+		}
+		if (err == nil) != v.Valid {
+			t.Errorf("parseMatrixFlags() incorrect error return for `%v`\n  want valid: `%v`\n  got: `%v`",
+				v.Args,
+				v.Valid,
+				err)
+			continue
+		}
+		if !v.Valid {
+			continue
+		}
+
+		if v.Jobs != 0 && opts.jobs != v.Jobs {
+			t.Errorf("parseMatrixFlags() returning incorrect jobs value\n  want: `%v`\n  got: `%v`",
+				v.Jobs,
+				opts.jobs)
+		}
+		if opts.jobs < 1 {
+			t.Errorf("parseMatrixFlags() returning invalid jobs value `%v`", opts.jobs)
+		}
+		tests := []struct {
+			field     string
+			want, got interface{}
+		}{
+			{"only", v.Only, opts.selector.only},
+			{"logDir", v.LogDir, opts.logDir},
+			{"cmdArgs", v.CmdArgs, opts.cmdArgs},
+		}
+		for _, tt := range tests {
+			if !reflect.DeepEqual(tt.want, tt.got) {
+				t.Errorf("parseMatrixFlags() returning incorrect %s value for `%v`\n  want: `%v`\n  got: `%v`",
+					tt.field,
+					v.Args,
+					tt.want,
+					tt.got)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// Version of the JSON documents displayed by query commands. Fields may be
+// added without changing the version; renaming or removing a field, or
+// changing its meaning, requires a new version.
+const OutputSchemaVersion = 1
+
+// formatFlag is the `--format` flag shared by query commands such as `ls`.
+var formatFlag = Flag{
+	Name:  `format`,
+	Value: `FORMAT`,
+	Usage: "display as `json`, `tsv`, or a Go template, e.g. '{{.TagLabel}} {{.Home}}'",
+}
+
+// outputFormat is the output format requested of a query command.
+type outputFormat struct {
+	name string             // ``, `json`, `tsv` or `template`
+	tmpl *template.Template // template for the `template` format
+}
+
+// queryDocument is the versioned JSON document displayed by query commands.
+type queryDocument struct {
+	SchemaVersion int         `json:"schema_version"`
+	Kind          string      `json:"kind"`
+	Items         interface{} `json:"items"`
+}
+
+// parseOutputFormat returns the output format requested by the `--format` flag
+// of a query command, or by the global `--json` option. The default format
+// has an empty name and is the command's human readable output.
+func parseOutputFormat(ctx *env.Context) (f outputFormat, err error) {
+	spec := ctx.Flag(`format`)
+	if spec == `` && ctx.Options.JSON {
+		spec = `json`
+	}
+
+	switch spec {
+	case ``, `json`, `tsv`:
+		f.name = spec
+	default:
+		if !strings.Contains(spec, `{{`) {
+			return f, fmt.Errorf("[ERROR] unknown format `%s`; use `json`, `tsv`, or a Go template.", spec)
+		}
+		f.name = `template`
+		f.tmpl, err = template.New(`format`).Option(`missingkey=error`).Parse(spec)
+		if err != nil {
+			return f, fmt.Errorf("[ERROR] invalid format template\n  %v", err)
+		}
+	}
+
+	return
+}
+
+// isDefault indicates whether the command's human readable output was
+// requested.
+func (f outputFormat) isDefault() bool { return f.name == `` }
+
+// write displays items, a slice of structs, in the requested format:
+//
+//    json      a queryDocument of the given kind containing the items
+//    tsv       one line per item with the tab separated values of its fields
+//    template  one line per item generated by executing the template
+//
+// Struct fields are tagged with their JSON name, and their declaration order
+// is the column order of the `tsv` format.
+func (f outputFormat) write(ctx *env.Context, kind string, items interface{}) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		panic("outputFormat.write requires a slice of items")
+	}
+
+	switch f.name {
+	case `json`:
+		enc := json.NewEncoder(ctx.Stdout)
+		enc.SetIndent(``, `  `)
+		doc := queryDocument{SchemaVersion: OutputSchemaVersion, Kind: kind, Items: items}
+		if err := enc.Encode(doc); err != nil {
+			return fmt.Errorf("---> unable to write JSON output (%s)", err)
+		}
+	case `tsv`:
+		for i := 0; i < v.Len(); i++ {
+			fmt.Fprintln(ctx.Stdout, strings.Join(tsvFields(v.Index(i)), "\t"))
+		}
+	case `template`:
+		for i := 0; i < v.Len(); i++ {
+			var b strings.Builder
+			if err := f.tmpl.Execute(&b, v.Index(i).Interface()); err != nil {
+				return fmt.Errorf("[ERROR] unable to apply format template\n  %v", err)
+			}
+			fmt.Fprintln(ctx.Stdout, strings.TrimSuffix(b.String(), "\n"))
+		}
+	}
+
+	return nil
+}
+
+// tsvFields returns the values of a struct's fields with any tabs and line
+// breaks replaced by spaces. String slices are joined by commas.
+func tsvFields(v reflect.Value) []string {
+	fields := make([]string, v.NumField())
+	r := strings.NewReplacer("\t", ` `, "\r", ` `, "\n", ` `)
+	for i := range fields {
+		if l, ok := v.Field(i).Interface().([]string); ok {
+			fields[i] = r.Replace(strings.Join(l, `,`))
+			continue
+		}
+		fields[i] = r.Replace(fmt.Sprint(v.Field(i).Interface()))
+	}
+
+	return fields
+}
@@ -0,0 +1,108 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	var tests = []struct {
+		flag string
+		json bool
+		want string
+	}{
+		{``, false, ``},
+		{``, true, `json`},
+		{`json`, false, `json`},
+		{`tsv`, true, `tsv`},
+		{`{{.TagLabel}}`, false, `template`},
+	}
+
+	for _, v := range tests {
+		ctx := env.NewContext()
+		if v.flag != `` {
+			ctx.SetFlags(map[string][]string{`format`: {v.flag}})
+		}
+		ctx.Options.JSON = v.json
+
+		f, err := parseOutputFormat(ctx)
+		if err != nil || f.name != v.want {
+			t.Errorf("parseOutputFormat() not returning correct value for `%s`\n  want: `%v`\n  got: `%v` (%v)",
+				v.flag, v.want, f.name, err)
+		}
+	}
+
+	for _, spec := range []string{`yaml`, `{{.TagLabel`} {
+		ctx := env.NewContext()
+		ctx.SetFlags(map[string][]string{`format`: {spec}})
+		if _, err := parseOutputFormat(ctx); err == nil {
+			t.Errorf("parseOutputFormat() not returning error for `%s`", spec)
+		}
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	newCtx := func(format string) (*env.Context, *bytes.Buffer) {
+		var out bytes.Buffer
+		ctx := env.NewContext()
+		ctx.Stdout = &out
+		ctx.SetFlags(map[string][]string{`format`: {format}})
+		ctx.Registry.Rubies = env.RubyMap{
+			`3574260220`: env.Ruby{ID: `2.2.3-p146`, TagLabel: `223p146`, Exe: `ruby`,
+				Home: `/rubies/ruby-2.2.3/bin`, Description: "ruby 2.2.3p146\t(2015-08-18) [x86_64-linux]"},
+			`1234567890`: env.Ruby{ID: `9.4.2`, TagLabel: `942`, Exe: `jruby`,
+				Home: `/rubies/jruby-9.4.2/bin`, Description: `jruby 9.4.2.0 (3.1.0)`},
+		}
+		return ctx, &out
+	}
+
+	ctx, out := newCtx(`json`)
+	if err := list(ctx); err != nil {
+		t.Fatalf("list() returned error: %v", err)
+	}
+	var doc struct {
+		SchemaVersion int          `json:"schema_version"`
+		Kind          string       `json:"kind"`
+		Items         []rubyRecord `json:"items"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("list() generated invalid JSON: %v", err)
+	}
+	if doc.SchemaVersion != OutputSchemaVersion || doc.Kind != `rubies` || len(doc.Items) != 2 {
+		t.Fatalf("list() generated incorrect JSON document: %+v", doc)
+	}
+	if r := doc.Items[0]; r.TagLabel != `223p146` || r.TagHash != `3574260220` || r.Current ||
+		r.Description != "ruby 2.2.3p146\t(2015-08-18) [x86_64-linux]" {
+		t.Errorf("list() generated incorrect JSON ruby: %+v", r)
+	}
+
+	var tests = []struct {
+		format string
+		want   string
+	}{
+		{`tsv`, "223p146\t3574260220\tfalse\t2.2.3-p146\truby\t/rubies/ruby-2.2.3/bin\t\t\t\truby 2.2.3p146 (2015-08-18) [x86_64-linux]\n" +
+			"942\t1234567890\tfalse\t9.4.2\tjruby\t/rubies/jruby-9.4.2/bin\t\t\t\tjruby 9.4.2.0 (3.1.0)\n"},
+		{`{{.TagLabel}} {{.Home}}`, "223p146 /rubies/ruby-2.2.3/bin\n942 /rubies/jruby-9.4.2/bin\n"},
+	}
+	for _, v := range tests {
+		ctx, out := newCtx(v.format)
+		if err := list(ctx); err != nil {
+			t.Errorf("list() returned error for `%s`: %v", v.format, err)
+			continue
+		}
+		if got := out.String(); got != v.want {
+			t.Errorf("list() not displaying correct `%s` output\n  want: `%q`\n  got: `%q`", v.format, v.want, got)
+		}
+	}
+
+	ctx, _ = newCtx(`{{.Bogus}}`)
+	if err := list(ctx); err == nil {
+		t.Error("list() not returning error for a template using an unknown field")
+	}
+}
@@ -0,0 +1,49 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var pickCmd *Command = &Command{
+	Name:    "pick",
+	Aliases: []string{"pick"},
+	Usage:   "pick [FILTER]",
+	Eg:      "pick 3.2",
+	Short:   "pick a registered ruby to use from an interactive list",
+	Long: `Picks the ruby to use from an interactive list of the registered rubies,
+initially showing those matching FILTER. Type to filter the list by tag,
+description or home, move with the up and down arrow keys or Ctrl-P and
+Ctrl-N, press Enter to pick, and Esc to exit without picking.
+
+When stdin or stdout isn't a terminal, and on Windows consoles, uru asks for
+the number of the ruby to use instead.`,
+	Flags: []Flag{},
+	Run:   pick,
+}
+
+func init() {
+	CmdRouter.Handle(pickCmd.Aliases, pickCmd)
+}
+
+// Pick the ruby to use from all registered rubies, initially showing those
+// matching the optional filter.
+func pick(ctx *env.Context) error {
+	if len(ctx.Registry.Rubies) == 0 {
+		fmt.Fprintln(ctx.Stdout, "---> No rubies registered with uru")
+		return nil
+	}
+
+	filter := strings.Join(ctx.CmdArgs(), ` `)
+	tagHash, err := env.PickRuby(ctx, ctx.Registry.Rubies, `registered rubies`, `use`, filter)
+	if err != nil {
+		return selectionError(err)
+	}
+
+	return activateRuby(ctx, tagHash, ``)
+}
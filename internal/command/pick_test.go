@@ -0,0 +1,65 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// scriptedTerminal is a fake terminal whose keystrokes are scripted by the
+// context's Stdin.
+type scriptedTerminal struct{}
+
+func (scriptedTerminal) IsTerminal() bool               { return true }
+func (scriptedTerminal) IsInputTerminal() bool          { return true }
+func (scriptedTerminal) MakeRaw() (func() error, error) { return func() error { return nil }, nil }
+
+func TestPick(t *testing.T) {
+	newCtx := func(script string) (*env.Context, *bytes.Buffer) {
+		var out bytes.Buffer
+		ctx := env.NewContext()
+		ctx.SetHome(t.TempDir())
+		ctx.Options.Shell = `bash`
+		ctx.Stdin, ctx.Stdout, ctx.Terminal = strings.NewReader(script), &out, scriptedTerminal{}
+		ctx.Registry.Rubies = env.RubyMap{
+			`3574260220`: env.Ruby{ID: `2.2.3-p146`, TagLabel: `223p146`, Exe: `ruby`, Home: `/rubies/ruby-2.2.3/bin`},
+			`1078060107`: env.Ruby{ID: `9.4.2`, TagLabel: `942`, Exe: `jruby`, Home: `/rubies/jruby-9.4.2/bin`},
+		}
+		return ctx, &out
+	}
+
+	ctx, out := newCtx("\x1b[B\r")
+	ctx.SetCmdAndArgs(`pick`, nil)
+	if err := CmdRouter.Dispatch(ctx, `pick`); err != nil {
+		t.Fatalf("pick returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "---> now using jruby 9.4.2 tagged as `942`") {
+		t.Errorf("pick not using the picked ruby\n  got: %q", out.String())
+	}
+	b, err := ioutil.ReadFile(filepath.Join(ctx.Home(), `uru_lackee`))
+	if err != nil || !strings.Contains(string(b), `/rubies/jruby-9.4.2/bin`) {
+		t.Errorf("pick not creating a switcher script for the picked ruby\n  got: %q (%v)", b, err)
+	}
+
+	ctx, _ = newCtx("\r")
+	ctx.SetCmdAndArgs(`pick`, []string{`jruby`})
+	if err := CmdRouter.Dispatch(ctx, `pick`); err != nil {
+		t.Fatalf("pick returned error: %v", err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(ctx.Home(), `uru_lackee`)); !strings.Contains(string(b), `jruby-9.4.2`) {
+		t.Errorf("pick not initially filtering the rubies\n  got: %q", b)
+	}
+
+	ctx, _ = newCtx("\x1b")
+	ctx.SetCmdAndArgs(`pick`, nil)
+	if err := CmdRouter.Dispatch(ctx, `pick`); ExitCode(err) != ExitError {
+		t.Errorf("pick not failing when exited without picking a ruby\n  got: `%v`", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
+)
+
+// Prefix of the executable names of external plugin commands, e.g. the
+// `uru-outdated` executable implements the `uru outdated` plugin command.
+const pluginPrefix = `uru-`
+
+var pluginNameRegex, pluginShortRegex *regexp.Regexp
+
+func init() {
+	var err error
+	pluginNameRegex, err = regexp.Compile(`\A[A-Za-z][\w-]*\z`)
+	if err != nil {
+		panic("unable to compile plugin name regexp")
+	}
+
+	pluginShortRegex, err = regexp.Compile(`uru-short:\s*(.+?)\s*$`)
+	if err != nil {
+		panic("unable to compile plugin description regexp")
+	}
+}
+
+// Default handler of the top-level command router. Unknown commands select a
+// ruby to use, unless they match no registered ruby but name an external
+// `uru-NAME` plugin, which is then run.
+func pluginOrUse(ctx *env.Context) error {
+	if exe, ok := findPlugin(ctx, ctx.Cmd()); ok {
+		return exitWith(runPlugin(ctx, exe))
+	}
+
+	return use(ctx)
+}
+
+// pluginDirs returns the dirs searched for plugins in search order: uru's
+// plugins dir followed by the dirs on PATH.
+func pluginDirs(ctx *env.Context) []string {
+	return append([]string{filepath.Join(ctx.Home(), `plugins`)},
+		filepath.SplitList(os.Getenv(`PATH`))...)
+}
+
+// findPlugin returns the full path of the executable implementing the named
+// plugin command. Reserved tag labels, gemset names, names matching a
+// registered ruby, and names not starting with a letter never name a plugin,
+// so a plugin can't take over switching rubies.
+func findPlugin(ctx *env.Context, name string) (string, bool) {
+	if !pluginNameRegex.MatchString(name) {
+		return ``, false
+	}
+	if rsvd, _ := isTagLabelReserved(name); rsvd {
+		return ``, false
+	}
+	if _, err := env.VersionFragmentToTag(ctx, name); err == nil {
+		return ``, false
+	}
+
+	exe, err := findExecutable(pluginPrefix+name, pluginDirs(ctx))
+	if err != nil {
+		return ``, false
+	}
+
+	return exe, true
+}
+
+// discoverPlugins returns a command for each plugin found in the plugin dirs,
+// sorted by name. A plugin found in more than one dir is shadowed by the first.
+func discoverPlugins(ctx *env.Context) (plugins []*Command) {
+	seen := make(map[string]bool)
+	for _, dir := range pluginDirs(ctx) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if !strings.HasPrefix(name, pluginPrefix) || e.IsDir() {
+				continue
+			}
+			name = strings.TrimPrefix(name, pluginPrefix)
+			if runtime.GOOS == `windows` {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if seen[name] {
+				continue
+			}
+
+			exe, ok := findPlugin(ctx, name)
+			if !ok {
+				continue
+			}
+			seen[name] = true
+
+			plugins = append(plugins, &Command{
+				Name:     name,
+				Aliases:  []string{name},
+				Usage:    fmt.Sprintf("%s ARGS...", name),
+				Eg:       name,
+				Short:    pluginShort(exe),
+				Long:     exe,
+				IsPlugin: true,
+			})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	return
+}
+
+// pluginShort returns the single line description a plugin declares with a
+// `uru-short: DESCRIPTION` line, typically a comment, near the start of its
+// executable file.
+func pluginShort(exe string) string {
+	f, err := os.Open(exe)
+	if err != nil {
+		return "plugin command"
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(io.LimitReader(f, 4096))
+	for s.Scan() {
+		if m := pluginShortRegex.FindStringSubmatch(s.Text()); m != nil {
+			return m[1]
+		}
+	}
+
+	return "plugin command"
+}
+
+// pluginEnviron returns the environment of a plugin process: uru's environment
+// plus the uru context exported as URU_* env vars.
+func pluginEnviron(ctx *env.Context) []string {
+	var tag, rbHome, gemHome, gemset string
+	if tagHash, info, err := env.CurrentRubyInfo(ctx); err == nil && tagHash != `` {
+		if ri, ok := ctx.Registry.Rubies[tagHash]; ok {
+			info = ri
+		}
+		tag, rbHome, gemHome = info.TagLabel, info.Home, info.GemHome
+		if name, dir := activeGemset(ctx, info); name != `` {
+			gemset, gemHome = name, dir
+		}
+	}
+
+	return append(os.Environ(),
+		fmt.Sprintf("URU_HOME=%s", ctx.Home()),
+		fmt.Sprintf("URU_REGISTRY=%s", filepath.Join(ctx.Home(), `rubies.json`)),
+		fmt.Sprintf("URU_RUBY_TAG=%s", tag),
+		fmt.Sprintf("URU_RUBY_HOME=%s", rbHome),
+		fmt.Sprintf("URU_GEM_HOME=%s", gemHome),
+		fmt.Sprintf("URU_GEMSET=%s", gemset),
+	)
+}
+
+// Runs the plugin executable with the command's args and the uru context
+// exported in its environment, returning the plugin's exit code.
+//
+// Plugins receive the following env vars, empty if no ruby is active:
+//
+//    URU_HOME         uru's home dir
+//    URU_REGISTRY     path of the registered rubies file
+//    URU_RUBY_TAG     tag label of the active ruby
+//    URU_RUBY_HOME    bin dir of the active ruby
+//    URU_GEM_HOME     gem home of the active ruby or gemset
+//    URU_GEMSET       name of the active gemset, `gemset` for a project gemset
+func runPlugin(ctx *env.Context, exe string) int {
+	log.Debug("plugin", "name", ctx.Cmd(), "exe", exe)
+
+	runner := exec.Command(exe, ctx.CmdArgs()...)
+	runner.Env = pluginEnviron(ctx)
+	runner.Stdin = ctx.Stdin
+	runner.Stdout = ctx.Stdout
+	runner.Stderr = ctx.Stderr
+
+	return runForwardingSignals(ctx, runner, fmt.Sprintf("%s%s", pluginPrefix, ctx.Cmd()))
+}
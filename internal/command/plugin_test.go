@@ -0,0 +1,90 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestPlugins(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip("plugin test scripts require a *nix shell")
+	}
+
+	home, err := ioutil.TempDir(``, `uru-home`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	pluginDir := filepath.Join(home, `plugins`)
+	os.MkdirAll(pluginDir, 0750)
+	ioutil.WriteFile(filepath.Join(pluginDir, `uru-hello`),
+		[]byte("#!/bin/sh\n# uru-short: say hello\necho hello\n"), 0750)
+	ioutil.WriteFile(filepath.Join(pluginDir, `uru-noexec`), []byte("#!/bin/sh\n"), 0640)
+
+	origPath := os.Getenv(`PATH`)
+	defer os.Setenv(`PATH`, origPath)
+	os.Setenv(`PATH`, ``)
+
+	ctx := env.NewContext()
+	ctx.SetHome(home)
+
+	if _, ok := findPlugin(ctx, `hello`); !ok {
+		t.Error("findPlugin() not finding plugin in uru's plugins dir")
+	}
+	for _, name := range []string{`noexec`, `missing`, `auto`, `223p146`} {
+		if _, ok := findPlugin(ctx, name); ok {
+			t.Errorf("findPlugin() finding non-plugin `%s`", name)
+		}
+	}
+
+	plugins := discoverPlugins(ctx)
+	if len(plugins) != 1 {
+		t.Fatalf("discoverPlugins() not returning correct number of plugins\n  want: `1`\n  got: `%d`",
+			len(plugins))
+	}
+	if p := plugins[0]; p.Name != `hello` || p.Short != `say hello` || !p.IsPlugin {
+		t.Errorf("discoverPlugins() not returning correct plugin\n  want: `hello: say hello`\n  got: `%s: %s`",
+			p.Name, p.Short)
+	}
+}
+
+func TestPluginsShadowedByRubies(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip("plugin test scripts require a *nix shell")
+	}
+
+	ctx := env.NewContext()
+	ctx.SetHome(t.TempDir())
+	ctx.Registry.Rubies = env.RubyMap{
+		`1889150228`: {TagLabel: `system`, Exe: `ruby`, Description: `ruby 3.0.2p107 (2021-07-07 revision 0db68f0233) [x86_64-linux]`},
+		`1234567890`: {TagLabel: `942`, Exe: `jruby`, Description: `jruby 9.4.2.0 (3.1.0)`},
+	}
+
+	pluginDir := filepath.Join(ctx.Home(), `plugins`)
+	os.MkdirAll(pluginDir, 0750)
+	for _, name := range []string{`system`, `jruby`, `hello`} {
+		ioutil.WriteFile(filepath.Join(pluginDir, pluginPrefix+name), []byte("#!/bin/sh\necho plugin\n"), 0750)
+	}
+	t.Setenv(`PATH`, ``)
+
+	for _, name := range []string{`system`, `jruby`} {
+		if _, ok := findPlugin(ctx, name); ok {
+			t.Errorf("findPlugin() finding plugin `%s` named like a registered ruby", name)
+		}
+	}
+	if _, ok := findPlugin(ctx, `hello`); !ok {
+		t.Error("findPlugin() not finding plugin not named like a registered ruby")
+	}
+	if plugins := discoverPlugins(ctx); len(plugins) != 1 || plugins[0].Name != `hello` {
+		t.Errorf("discoverPlugins() listing plugins named like a registered ruby\n  got: `%v`", plugins)
+	}
+}
@@ -0,0 +1,200 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// reportSpec identifies the format and destination file of a report generated
+// from the results of running a command with multiple rubies.
+type reportSpec struct {
+	format string // `junit` or `json`
+	path   string
+}
+
+// parseReportSpec parses a `--report FORMAT=PATH` option value.
+func parseReportSpec(spec string) (rs reportSpec, err error) {
+	parts := strings.SplitN(spec, `=`, 2)
+	if len(parts) != 2 || parts[1] == `` {
+		return rs, fmt.Errorf("[ERROR] invalid `--report %s` value; use FORMAT=PATH.", spec)
+	}
+
+	rs.format, rs.path = parts[0], parts[1]
+	switch rs.format {
+	case `junit`, `json`:
+	default:
+		return rs, fmt.Errorf("[ERROR] unknown report format `%s`; use `junit` or `json`.", rs.format)
+	}
+
+	return
+}
+
+// writeReports writes each requested report for the results of running the
+// named command line with multiple rubies.
+func writeReports(specs []reportSpec, cmdLine string, results []*rubyResult) error {
+	for _, rs := range specs {
+		f, err := os.Create(rs.path)
+		if err != nil {
+			return fmt.Errorf("---> unable to create `%s` report", rs.path)
+		}
+
+		switch rs.format {
+		case `junit`:
+			err = writeJUnitReport(f, cmdLine, results)
+		case `json`:
+			err = writeJSONReport(f, cmdLine, results)
+		}
+		if e := f.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return fmt.Errorf("---> unable to write `%s` report", rs.path)
+		}
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName  string          `xml:"classname,attr"`
+	Name       string          `xml:"name,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+	Error      *junitFailure   `xml:"error,omitempty"`
+	SystemErr  string          `xml:"system-err,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// writeJUnitReport writes a JUnit XML report containing a single test suite
+// for the command line with one test case per ruby.
+func writeJUnitReport(w io.Writer, cmdLine string, results []*rubyResult) error {
+	suite := junitTestSuite{Name: cmdLine, Tests: len(results)}
+
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+
+		tc := junitTestCase{
+			ClassName: fmt.Sprintf("%s.%s", env.AppName, r.Ruby.Exe),
+			Name:      r.Ruby.TagLabel,
+			Time:      fmt.Sprintf("%.3f", r.Duration.Seconds()),
+			Properties: []junitProperty{
+				{`id`, r.Ruby.ID},
+				{`tag_hash`, r.TagHash},
+				{`description`, r.Ruby.Description},
+				{`exit_status`, fmt.Sprintf("%d", r.ExitCode)},
+			},
+			SystemErr: string(r.Stderr),
+		}
+		switch {
+		case r.Err != nil:
+			suite.Errors++
+			tc.Error = &junitFailure{Message: r.Err.Error(), Type: `error`}
+		case r.ExitCode != 0:
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: fmt.Sprintf("exit status %d", r.ExitCode), Type: `exit`}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent(``, `  `)
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+type jsonReport struct {
+	Command string           `json:"command"`
+	Passed  bool             `json:"passed"`
+	Results []jsonRubyResult `json:"results"`
+}
+
+type jsonRubyResult struct {
+	TagLabel    string  `json:"tag_label"`
+	TagHash     string  `json:"tag_hash"`
+	ID          string  `json:"id"`
+	Exe         string  `json:"exe"`
+	Description string  `json:"description"`
+	Passed      bool    `json:"passed"`
+	ExitStatus  int     `json:"exit_status"`
+	Duration    float64 `json:"duration_seconds"`
+	Error       string  `json:"error,omitempty"`
+	Stderr      string  `json:"stderr"`
+}
+
+// writeJSONReport writes a JSON report for the command line containing one
+// result object per ruby.
+func writeJSONReport(w io.Writer, cmdLine string, results []*rubyResult) error {
+	report := jsonReport{Command: cmdLine, Passed: true, Results: []jsonRubyResult{}}
+
+	for _, r := range results {
+		jr := jsonRubyResult{
+			TagLabel:    r.Ruby.TagLabel,
+			TagHash:     r.TagHash,
+			ID:          r.Ruby.ID,
+			Exe:         r.Ruby.Exe,
+			Description: r.Ruby.Description,
+			Passed:      r.Passed(),
+			ExitStatus:  r.ExitCode,
+			Duration:    r.Duration.Seconds(),
+			Stderr:      string(r.Stderr),
+		}
+		if r.Err != nil {
+			jr.Error = r.Err.Error()
+		}
+		if !jr.Passed {
+			report.Passed = false
+		}
+		report.Results = append(report.Results, jr)
+	}
+
+	b, err := json.MarshalIndent(report, ``, `  `)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+
+	return err
+}
@@ -0,0 +1,82 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var testReportResults = []*rubyResult{
+	{
+		TagHash:  `3577244517`,
+		Ruby:     env.Ruby{ID: `2.1.1-p1`, TagLabel: `211p1`, Exe: `ruby`},
+		Duration: 1500 * time.Millisecond,
+	},
+	{
+		TagHash:  `444332046`,
+		Ruby:     env.Ruby{ID: `1.7.9`, TagLabel: `179`, Exe: `jruby`},
+		ExitCode: 2,
+		Stderr:   []byte("LoadError"),
+	},
+	{
+		TagHash: `3091568265`,
+		Ruby:    env.Ruby{ID: `1.7.10`, TagLabel: `1710`, Exe: `jruby`},
+		Err:     errors.New("executable `rake` not found on PATH"),
+	},
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJUnitReport(&buf, `rake test`, testReportResults); err != nil {
+		t.Fatalf("writeJUnitReport() returned error: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("writeJUnitReport() generated invalid XML: %v", err)
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("writeJUnitReport() generated `%d` test suites, want 1", len(suites.Suites))
+	}
+	s := suites.Suites[0]
+	if s.Tests != 3 || s.Failures != 1 || s.Errors != 1 {
+		t.Errorf("writeJUnitReport() incorrect counts\n  want: tests=3 failures=1 errors=1\n  got: tests=%d failures=%d errors=%d",
+			s.Tests, s.Failures, s.Errors)
+	}
+	if s.Cases[1].Name != `179` || s.Cases[1].SystemErr != `LoadError` || s.Cases[1].Failure == nil {
+		t.Errorf("writeJUnitReport() incorrect failing test case: %+v", s.Cases[1])
+	}
+	if !strings.Contains(buf.String(), `name="exit_status" value="2"`) {
+		t.Error("writeJUnitReport() missing exit status property")
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSONReport(&buf, `rake test`, testReportResults); err != nil {
+		t.Fatalf("writeJSONReport() returned error: %v", err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("writeJSONReport() generated invalid JSON: %v", err)
+	}
+	if report.Passed || len(report.Results) != 3 {
+		t.Fatalf("writeJSONReport() incorrect summary: %+v", report)
+	}
+	if r := report.Results[0]; !r.Passed || r.TagLabel != `211p1` || r.Duration != 1.5 {
+		t.Errorf("writeJSONReport() incorrect passing result: %+v", r)
+	}
+	if r := report.Results[2]; r.Passed || r.Error == `` {
+		t.Errorf("writeJSONReport() incorrect error result: %+v", r)
+	}
+}
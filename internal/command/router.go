@@ -5,12 +5,12 @@ package command
 
 import (
 	"fmt"
-	"log"
+	"strings"
 
 	"bitbucket.org/jonforums/uru/internal/env"
 )
 
-type HandlerFunc func(*env.Context)
+type HandlerFunc func(*env.Context) error
 
 type Router struct {
 	// Registry commands indexed by all their known command aliases. A single
@@ -32,7 +32,7 @@ type Router struct {
 // Returns a newly configured, ready-to-use command router. Provide a non-nil
 // handler function with the following signature
 //
-//      func(*cmd.Context)
+//      func(*cmd.Context) error
 //
 // and the router will use function as the default that will be called when
 // no registerd commands match the command requested in the user specified
@@ -85,28 +85,95 @@ func (r *Router) Handle(cmds []string, handler *Command) {
 		}
 	}
 
-	if n := handler.Name; n != "help" && n != "version" {
+	if n := handler.Name; n != "help" && n != "version" && !handler.Hidden {
 		r.commands[n] = handler
 	}
 }
 
 // Dispatch calls the `Run` method of a previously registerd command instance
 // corresponding to the user specified command string, passing a context as
-// the only arg. If the command string is not a recognized command, and the
-// command router instance has been created with a non-nil default handler,
-// the default handler will be invoked with a context as the only arg.
-func (r *Router) Dispatch(ctx *env.Context, cmd string) {
-	if c, ok := r.handlers[cmd]; ok {
-		if c.Runnable() {
-			c.Run(ctx)
-		} else {
-			fmt.Println(c.Long)
+// the only arg. The command line flags of a command having a Flags spec are
+// parsed into the context, leaving only the positional args as the context's
+// command args. A command having sub-commands dispatches its first arg to its
+// child router, recursing until a runnable command is found. If the command
+// string is not a recognized command, and the command router instance has been
+// created with a non-nil default handler, the default handler will be invoked
+// with a context as the only arg. An unrecognized `help` sub-command displays
+// the help of its parent command. Dispatch returns the error returned by the
+// invoked command or handler.
+func (r *Router) Dispatch(ctx *env.Context, cmd string) error {
+	return r.dispatch(ctx, nil, cmd)
+}
+
+// dispatch routes the command given the path of parent command names leading
+// to this router; the path is empty for the top-level router.
+func (r *Router) dispatch(ctx *env.Context, path []string, cmd string) error {
+	c, ok := r.handlers[cmd]
+	if !ok {
+		switch {
+		case r.defHandler != nil:
+			return r.defHandler(ctx)
+		case len(path) > 0 && cmd == `help`:
+			// `uru admin help SUBCMD` displays `uru help admin SUBCMD`
+			ctx.SetCmdArgs(append(append([]string{}, path...), ctx.CmdArgs()...))
+			return help(ctx)
+		case len(path) > 0:
+			hint := ``
+			if s := didYouMean(r.suggestCommands(cmd)); s != `` {
+				hint = fmt.Sprintf("\n---> %s", strings.TrimPrefix(s, `; `))
+			}
+			return fmt.Errorf("[ERROR] I don't understand the `%s %s` sub-command%s\n---> see `%s help %s` for the available sub-commands",
+				commandPath(path), cmd, hint, env.AppName, strings.Join(path, ` `))
+		default:
+			return fmt.Errorf("command/router: no default handler registered to process '%s' command", cmd)
+		}
+	}
+
+	switch {
+	case c.Subcommands != nil:
+		path = append(path[:len(path):len(path)], c.Name)
+		args := ctx.CmdArgs()
+		if len(args) == 0 {
+			return fmt.Errorf("[ERROR] must specify a `%s` sub-command\n---> see `%s help %s` for the available sub-commands",
+				commandPath(path), env.AppName, strings.Join(path, ` `))
 		}
-	} else {
-		if r.defHandler != nil {
-			r.defHandler(ctx)
-		} else {
-			log.Fatal(fmt.Errorf("command/router: no default handler registered to process '%s' command", cmd))
+		ctx.SetCmd(args[0])
+		ctx.SetCmdArgs(args[1:])
+		return c.Subcommands.dispatch(ctx, path, args[0])
+	case c.Runnable():
+		if c.Flags != nil {
+			flags, args, err := c.parseFlags(ctx.CmdArgs())
+			if err != nil {
+				return err
+			}
+			ctx.SetFlags(flags)
+			ctx.SetCmdArgs(args)
 		}
+		return c.Run(ctx)
+	default:
+		fmt.Fprintln(ctx.Stdout, c.Long)
 	}
+
+	return nil
+}
+
+// commandPath returns the user visible command line of a path of command
+// names, e.g. `uru admin gemset`.
+func commandPath(path []string) string {
+	return fmt.Sprintf("%s %s", env.AppName, strings.Join(path, ` `))
+}
+
+// Walk returns the command named by a path of command aliases, following the
+// child routers of commands having sub-commands, and the number of path
+// elements used. Walking stops at the first element not naming a sub-command.
+func (r *Router) Walk(path []string) (cmd *Command, n int) {
+	for rtr := r; n < len(path) && rtr != nil; n++ {
+		c, err := rtr.Handler(path[n])
+		if err != nil {
+			break
+		}
+		cmd, rtr = c, c.Subcommands
+	}
+
+	return
 }
@@ -7,13 +7,14 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 
 	"bitbucket.org/jonforums/uru/internal/env"
 )
 
 func TestRouterConfig(t *testing.T) {
-	r := NewRouter(func(ctx *env.Context) {})
+	r := NewRouter(func(ctx *env.Context) error { return nil })
 	r.Handle([]string{`gem`}, &Command{})
 	r.Handle([]string{`ls`, `list`}, &Command{})
 
@@ -35,9 +36,9 @@ func TestRouterDispatch(t *testing.T) {
 	ctx := env.NewContext()
 
 	defExpected := "default_test"
-	r := NewRouter(func(*env.Context) { fmt.Fprintf(out, "%s", defExpected) })
-	r.Handle([]string{`admin`}, &Command{Run: func(*env.Context) { fmt.Fprintf(out, "%s", "admin_test") }})
-	r.Handle([]string{`gem`}, &Command{Run: func(*env.Context) { fmt.Fprintf(out, "%s", "gem_test") }})
+	r := NewRouter(func(*env.Context) error { fmt.Fprintf(out, "%s", defExpected); return nil })
+	r.Handle([]string{`admin`}, &Command{Run: func(*env.Context) error { fmt.Fprintf(out, "%s", "admin_test"); return nil }})
+	r.Handle([]string{`gem`}, &Command{Run: func(*env.Context) error { fmt.Fprintf(out, "%s", "gem_test"); return nil }})
 
 	// test registered command routing
 	for _, c := range []string{`admin`, `gem`} {
@@ -62,6 +63,32 @@ func TestRouterDispatch(t *testing.T) {
 	}
 }
 
+func TestRouterDispatchFlags(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetCmdArgs([]string{`--verbose`, `dir`})
+
+	var verbose bool
+	var args []string
+	r := NewRouter(nil)
+	r.Handle([]string{`ls`}, &Command{
+		Flags: []Flag{{Name: `verbose`}},
+		Run: func(ctx *env.Context) error {
+			verbose, args = ctx.IsFlagSet(`verbose`), ctx.CmdArgs()
+			return nil
+		},
+	})
+
+	r.Dispatch(ctx, `ls`)
+	if !verbose {
+		t.Error("Command route dispatch did not parse `--verbose` flag")
+	}
+	if len(args) != 1 || args[0] != `dir` {
+		t.Errorf("Command route dispatch failed to strip flags\n  want: `%v`\n  got: `%v`\n",
+			[]string{`dir`},
+			args)
+	}
+}
+
 func BenchmarkRegexCompare(b *testing.B) {
 	r, _ := regexp.Compile("gem")
 	for i := 0; i < b.N; i++ {
@@ -96,13 +123,13 @@ func BenchmarkCommandRouter(b *testing.B) {
 	ctx := env.NewContext()
 	cmds := []string{"admin", "gem", "help", "ls", "ruby", "version", "215"}
 
-	r := NewRouter(func(*env.Context) {})
-	r.Handle([]string{`admin`}, &Command{Run: func(ctx *env.Context) {}})
-	r.Handle([]string{`gem`}, &Command{Run: func(ctx *env.Context) {}})
-	r.Handle([]string{`help`}, &Command{Run: func(ctx *env.Context) {}})
-	r.Handle([]string{`ls`, `list`}, &Command{Run: func(ctx *env.Context) {}})
-	r.Handle([]string{`ruby`, `rb`}, &Command{Run: func(ctx *env.Context) {}})
-	r.Handle([]string{`ver`, `version`}, &Command{Run: func(ctx *env.Context) {}})
+	r := NewRouter(func(*env.Context) error { return nil })
+	r.Handle([]string{`admin`}, &Command{Run: func(ctx *env.Context) error { return nil }})
+	r.Handle([]string{`gem`}, &Command{Run: func(ctx *env.Context) error { return nil }})
+	r.Handle([]string{`help`}, &Command{Run: func(ctx *env.Context) error { return nil }})
+	r.Handle([]string{`ls`, `list`}, &Command{Run: func(ctx *env.Context) error { return nil }})
+	r.Handle([]string{`ruby`, `rb`}, &Command{Run: func(ctx *env.Context) error { return nil }})
+	r.Handle([]string{`ver`, `version`}, &Command{Run: func(ctx *env.Context) error { return nil }})
 
 	for i := 0; i < b.N; i++ {
 		for _, c := range cmds {
@@ -110,3 +137,102 @@ func BenchmarkCommandRouter(b *testing.B) {
 		}
 	}
 }
+
+func TestRouterDispatchSubcommands(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetCmdArgs([]string{`gemset`, `rm`, `--dry-run`, `32@rails7`})
+
+	var cmd string
+	var args []string
+	var dryRun bool
+	leaves := NewRouter(nil)
+	leaves.Handle([]string{`rm`, `del`}, &Command{
+		Name:  `rm`,
+		Flags: []Flag{{Name: `dry-run`}},
+		Run: func(ctx *env.Context) error {
+			cmd, args, dryRun = ctx.Cmd(), ctx.CmdArgs(), ctx.IsFlagSet(`dry-run`)
+			return nil
+		},
+	})
+	mid := NewRouter(nil)
+	mid.Handle([]string{`gemset`, `gs`}, &Command{Name: `gemset`, Subcommands: leaves})
+	r := NewRouter(nil)
+	r.Handle([]string{`admin`}, &Command{Name: `admin`, Subcommands: mid})
+
+	r.Dispatch(ctx, `admin`)
+	if cmd != `rm` || !dryRun || len(args) != 1 || args[0] != `32@rails7` {
+		t.Errorf("Sub-command dispatch failed\n  want: `rm true [32@rails7]`\n  got: `%s %v %v`",
+			cmd, dryRun, args)
+	}
+
+	var tests = []struct {
+		path []string
+		want string
+		n    int
+	}{
+		{[]string{`admin`, `gs`, `del`}, `rm`, 3},
+		{[]string{`admin`, `gemset`, `bogus`}, `gemset`, 2},
+		{[]string{`admin`, `gemset`, `rm`, `32@rails7`}, `rm`, 3},
+		{[]string{`bogus`}, ``, 0},
+	}
+	for _, v := range tests {
+		c, n := r.Walk(v.path)
+		name := ``
+		if c != nil {
+			name = c.Name
+		}
+		if name != v.want || n != v.n {
+			t.Errorf("Router.Walk() not returning correct value for `%v`\n  want: `%s %d`\n  got: `%s %d`",
+				v.path, v.want, v.n, name, n)
+		}
+	}
+}
+
+func TestCmdRouterInProcess(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(`no-such-uru-home`)
+	ctx.Registry.Rubies = env.RubyMap{
+		`3574260220`: env.Ruby{TagLabel: `223p146`, Exe: `ruby`, ID: `2.2.3-p146`, Home: `/rubies/223/bin`},
+	}
+
+	var tests = []struct {
+		cmd  string
+		args []string
+		out  string
+		err  string
+		code int
+	}{
+		{`version`, nil, `uru v` + env.AppVersion, ``, ExitOK},
+		{`ls`, nil, `223p146`, ``, ExitOK},
+		{`admin`, nil, ``, "must specify a `uru admin` sub-command", ExitError},
+		{`admin`, []string{`gemset`, `bogus`}, ``, "`uru admin gemset bogus` sub-command", ExitError},
+		{`admin`, []string{`help`}, `Usage: uru admin SUBCMD ARGS`, ``, ExitOK},
+		{`admin`, []string{`help`, `gemset`, `rm`}, `Usage: uru admin gemset rm`, ``, ExitOK},
+		{`admin`, []string{`gemset`, `help`, `rm`}, `Usage: uru admin gemset rm`, ``, ExitOK},
+		{`admin`, []string{`retag`, `223p146`}, ``, "must specify both CURRENT and NEW tag labels", ExitError},
+		{`admin`, []string{`retag`, `223p146`, `auto`}, ``, "conflicts with reserved `auto`", ExitError},
+		{`ls`, []string{`--bogus`}, ``, "unknown flag `--bogus`", ExitError},
+		{`exec`, []string{`331`, `--`, `ruby`}, ``, "unable to find registered ruby matching `331`", ExitError},
+	}
+
+	for _, tt := range tests {
+		out := new(bytes.Buffer)
+		ctx.Stdout, ctx.Stderr = out, out
+		ctx.SetCmdAndArgs(tt.cmd, tt.args)
+		ctx.SetFlags(nil)
+
+		err := CmdRouter.Dispatch(ctx, tt.cmd)
+		msg := ``
+		if err != nil {
+			msg = err.Error()
+		}
+		if !strings.Contains(out.String(), tt.out) || !strings.Contains(msg, tt.err) || (tt.err == `` && err != nil) {
+			t.Errorf("Dispatch(%s %v) incorrect\n  want: `%s` / `%s`\n  got: `%s` / `%v`",
+				tt.cmd, tt.args, tt.out, tt.err, out.String(), err)
+		}
+		if code := ExitCode(err); code != tt.code {
+			t.Errorf("Dispatch(%s %v) exit code incorrect\n  want: `%v`\n  got: `%v`",
+				tt.cmd, tt.args, tt.code, code)
+		}
+	}
+}
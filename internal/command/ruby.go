@@ -10,19 +10,33 @@ import (
 var rubyCmd *Command = &Command{
 	Name:    "ruby",
 	Aliases: []string{"ruby", "rb"},
-	Usage:   "ruby ARGS...",
+	Usage:   "ruby [SELECT_OPTS] [--jobs N] [--fail-fast] [--report FMT=PATH] ARGS...",
 	Eg:      `ruby -e "puts RUBY_VERSION"`,
-	Short:   "run a ruby command with all registered rubies",
-	Run:     ruby,
+	Short:   "run a ruby command with registered rubies",
+	Long: `Runs ruby with ARGS with each registered ruby in turn, displaying each ruby's
+description before its output. SELECT_OPTS, the --only, --except, --engine,
+--ruby-version and --order flags, limit and order the rubies to run. Uru's
+flags end at -- or at the first arg not naming one of them, so ruby's own
+flags such as --version are passed to ruby.
+
+Uru exits with 2 if ruby failed with any ruby, or 3 if it couldn't be started
+with any ruby. The --fail-fast option stops after the first failing ruby,
+--jobs runs up to N rubies concurrently, and --report writes a junit or json
+report of the results to PATH.`,
+	Flags: multiRubyFlags(
+		Flag{Name: `jobs`, Value: `N`, Usage: "run up to N rubies concurrently, 1 by default"},
+		Flag{Name: `fail-fast`, Usage: "stop running rubies after the first failure"},
+		Flag{Name: `report`, Value: `FMT=PATH`, Repeated: true, Usage: "write a junit or json report of the results to PATH"},
+	),
+	ProgramArgs: true,
+	Run:         ruby,
 }
 
 func init() {
 	CmdRouter.Handle(rubyCmd.Aliases, rubyCmd)
 }
 
-func ruby(ctx *env.Context) {
+func ruby(ctx *env.Context) error {
 	ctx.SetCmd(`ruby`)
-	if err := rubyExec(ctx); err != nil {
-		// TODO implement me
-	}
+	return multiRubyExec(ctx)
 }
@@ -0,0 +1,266 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var versionNumRegex, constraintRegex *regexp.Regexp
+
+func init() {
+	var err error
+	versionNumRegex, err = regexp.Compile(`\A\d+(?:\.\d+)*`)
+	if err != nil {
+		panic("unable to compile ruby version number parsing regexp")
+	}
+
+	constraintRegex, err = regexp.Compile(`\A(>=|<=|!=|~>|>|<|=)?\s*(\d+(?:\.\d+)*)\z`)
+	if err != nil {
+		panic("unable to compile version requirement parsing regexp")
+	}
+}
+
+// rubySelector selects, and orders, the registered rubies used by multi-ruby
+// commands such as `uru ruby`, `uru gem` and `uru matrix`.
+type rubySelector struct {
+	only     []string // tag labels or IDs to include, all if empty
+	except   []string // tag labels or IDs to exclude
+	engines  []string // ruby engine exe names to include, all if empty
+	versions []versionConstraint
+	order    string // `tag` or `version`
+}
+
+// selectOptsFlags are the SELECT_OPTS flags shared by the multi-ruby commands
+// to choose the registered rubies to run.
+var selectOptsFlags = []Flag{
+	{Name: `only`, Value: `TAGS`, Repeated: true, Usage: "only rubies matching the comma separated tags"},
+	{Name: `except`, Value: `TAGS`, Repeated: true, Usage: "skip rubies matching the comma separated tags"},
+	{Name: `engine`, Value: `NAMES`, Repeated: true, Usage: "only rubies of the given engines, e.g. ruby,jruby"},
+	{Name: `ruby-version`, Value: `REQ`, Repeated: true, Usage: "only rubies meeting the requirement, e.g. '>= 3.0'"},
+	{Name: `order`, Value: `ORDER`, Usage: "run rubies in `tag` (default) or `version` order"},
+}
+
+// selectorFromFlags returns the selector configured by the SELECT_OPTS flags
+// given to a multi-ruby command.
+func selectorFromFlags(ctx *env.Context) (s rubySelector, err error) {
+	for _, f := range selectOptsFlags {
+		for _, v := range ctx.FlagValues(f.Name) {
+			if err = s.setOption(`--`+f.Name, v); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// setOption configures the selector from a command line option and its value.
+// Comma separated values of the list options are accumulated.
+func (s *rubySelector) setOption(opt, val string) (err error) {
+	list := func(v string) (l []string) {
+		for _, t := range strings.Split(v, `,`) {
+			if t = strings.TrimSpace(t); t != `` {
+				l = append(l, t)
+			}
+		}
+		return
+	}
+
+	switch opt {
+	case `--only`:
+		s.only = append(s.only, list(val)...)
+	case `--except`:
+		s.except = append(s.except, list(val)...)
+	case `--engine`:
+		s.engines = append(s.engines, list(val)...)
+	case `--ruby-version`:
+		vc, e := parseVersionConstraints(val)
+		if e != nil {
+			return e
+		}
+		s.versions = append(s.versions, vc...)
+	case `--order`:
+		if val != `tag` && val != `version` {
+			return fmt.Errorf("[ERROR] invalid `--order %s` value; use `tag` or `version`.", val)
+		}
+		s.order = val
+	default:
+		return fmt.Errorf("[ERROR] unknown option `%s`.", opt)
+	}
+
+	return
+}
+
+// selectRubies returns the tag hashes of the registered rubies matching the
+// selector, ordered by tag label or by version. Version order sorts newer
+// versions first and breaks ties by engine and tag label.
+func selectRubies(ctx *env.Context, s *rubySelector) ([]string, error) {
+	if len(ctx.Registry.Rubies) == 0 {
+		return nil, errors.New("---> No rubies registered with uru")
+	}
+
+	tags := make(env.RubyMap, len(ctx.Registry.Rubies))
+	if len(s.only) == 0 {
+		for t, ri := range ctx.Registry.Rubies {
+			tags[t] = ri
+		}
+	}
+	for _, l := range s.only {
+		matches, err := env.TagLabelToTag(ctx, l)
+		if err != nil {
+			return nil, noRubyError(ctx, l)
+		}
+		for t, ri := range matches {
+			tags[t] = ri
+		}
+	}
+	for _, l := range s.except {
+		matches, _ := env.TagLabelToTag(ctx, l)
+		for t := range matches {
+			delete(tags, t)
+		}
+	}
+
+	for t, ri := range tags {
+		if len(s.engines) > 0 && !containsString(s.engines, ri.Exe) {
+			delete(tags, t)
+			continue
+		}
+		for _, vc := range s.versions {
+			if !vc.match(ri.ID) {
+				delete(tags, t)
+				break
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("---> no registered rubies match the selection")
+	}
+
+	sortedTagHashes, err := env.SortTagsByTagLabel(&tags)
+	if err != nil {
+		return nil, err
+	}
+	if s.order == `version` {
+		sort.SliceStable(sortedTagHashes, func(i, j int) bool {
+			a, b := tags[sortedTagHashes[i]], tags[sortedTagHashes[j]]
+			if c := compareVersions(versionNumRegex.FindString(a.ID), versionNumRegex.FindString(b.ID)); c != 0 {
+				return c > 0
+			}
+			return a.Exe < b.Exe
+		})
+	}
+
+	return sortedTagHashes, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// versionConstraint is a single ruby version requirement such as `>= 3.0`.
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+// parseVersionConstraints parses a comma separated list of RubyGems style
+// version requirements such as `>= 2.7, < 3.2` or `~> 3.1`. A version without
+// an operator, e.g. `3.1`, matches all versions starting with it.
+func parseVersionConstraints(req string) (vcs []versionConstraint, err error) {
+	for _, r := range strings.Split(req, `,`) {
+		res := constraintRegex.FindStringSubmatch(strings.TrimSpace(r))
+		if res == nil {
+			return nil, fmt.Errorf("[ERROR] invalid version requirement `%s`.", strings.TrimSpace(r))
+		}
+		vcs = append(vcs, versionConstraint{op: res[1], version: res[2]})
+	}
+
+	return
+}
+
+// match indicates whether a ruby ID such as `2.3.1-p112` satisfies the
+// constraint. Only the leading dotted numeric part of the ID is compared.
+func (vc versionConstraint) match(id string) bool {
+	v := versionNumRegex.FindString(id)
+	if v == `` {
+		return false
+	}
+
+	c := compareVersions(v, vc.version)
+	switch vc.op {
+	case `>=`:
+		return c >= 0
+	case `<=`:
+		return c <= 0
+	case `>`:
+		return c > 0
+	case `<`:
+		return c < 0
+	case `!=`:
+		return !versionHasPrefix(v, vc.version)
+	case `~>`:
+		// pessimistic constraint: `~> 3.1` allows >= 3.1 and < 4.0 while
+		// `~> 3.1.2` allows >= 3.1.2 and < 3.2.0
+		segs := strings.Split(vc.version, `.`)
+		if len(segs) > 1 {
+			segs = segs[:len(segs)-1]
+		}
+		return c >= 0 && versionHasPrefix(v, strings.Join(segs, `.`))
+	}
+
+	return versionHasPrefix(v, vc.version)
+}
+
+// versionHasPrefix indicates whether the leading segments of a dotted version
+// are those of the prefix, e.g. `3.1.4` has prefix `3.1` but not `3.10`.
+func versionHasPrefix(version, prefix string) bool {
+	vs, ps := strings.Split(version, `.`), strings.Split(prefix, `.`)
+	if len(ps) > len(vs) {
+		return compareVersions(version, prefix) == 0
+	}
+	for i, p := range ps {
+		x, _ := strconv.Atoi(vs[i])
+		y, _ := strconv.Atoi(p)
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+// compareVersions numerically compares two dotted versions, treating missing
+// segments as zero, and returns -1, 0, or 1.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, `.`), strings.Split(b, `.`)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+var testSelectRubies = env.RubyMap{
+	`1001`: {ID: `3.2.2-p53`, TagLabel: `322p53`, Exe: `ruby`},
+	`1002`: {ID: `3.1.4-p223`, TagLabel: `314p223`, Exe: `ruby`},
+	`1003`: {ID: `2.7.8-p225`, TagLabel: `system`, Exe: `ruby`},
+	`1004`: {ID: `9.4.2`, TagLabel: `942`, Exe: `jruby`},
+	`1005`: {ID: `3.10.1`, TagLabel: `3101`, Exe: `ruby`},
+}
+
+var testVersionConstraints = []struct {
+	Req   string
+	ID    string
+	Match bool
+}{
+	{`>= 3.0`, `3.1.4-p223`, true},
+	{`>= 3.0`, `2.7.8-p225`, false},
+	{`< 3.2`, `3.10.1`, false},
+	{`3.1`, `3.1.4-p223`, true},
+	{`3.1`, `3.10.1`, false},
+	{`= 3.1.4`, `3.1.4-p223`, true},
+	{`!= 3.1`, `3.1.4-p223`, false},
+	{`~> 3.1`, `3.10.1`, true},
+	{`~> 3.1`, `4.0.0`, false},
+	{`~> 3.1.2`, `3.1.4-p223`, true},
+	{`~> 3.1.2`, `3.2.0`, false},
+	{`>= 2.7, < 3.2`, `3.1.4-p223`, true},
+	{`>= 2.7, < 3.2`, `3.2.2-p53`, false},
+}
+
+func TestVersionConstraintMatch(t *testing.T) {
+	for _, v := range testVersionConstraints {
+		vcs, err := parseVersionConstraints(v.Req)
+		if err != nil {
+			t.Errorf("parseVersionConstraints() returned error for `%s`: %v", v.Req, err)
+			continue
+		}
+		match := true
+		for _, vc := range vcs {
+			match = match && vc.match(v.ID)
+		}
+		if match != v.Match {
+			t.Errorf("version requirement `%s` incorrectly matched `%s`\n  want: `%v`\n  got: `%v`",
+				v.Req, v.ID, v.Match, match)
+		}
+	}
+
+	for _, req := range []string{`>= three`, `=> 3.0`, ``} {
+		if _, err := parseVersionConstraints(req); err == nil {
+			t.Errorf("parseVersionConstraints() should return error for `%s`", req)
+		}
+	}
+}
+
+func TestSelectRubies(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.Registry.Rubies = testSelectRubies
+
+	tests := []struct {
+		opts [][2]string
+		want []string
+	}{
+		{nil, []string{`3101`, `314p223`, `322p53`, `942`, `system`}},
+		{[][2]string{{`--only`, `31,32`}}, []string{`3101`, `314p223`, `322p53`}},
+		{[][2]string{{`--except`, `system`}, {`--engine`, `ruby`}}, []string{`3101`, `314p223`, `322p53`}},
+		{[][2]string{{`--ruby-version`, `>= 3.0`}, {`--order`, `version`}}, []string{`942`, `3101`, `322p53`, `314p223`}},
+		{[][2]string{{`--engine`, `jruby,ruby`}, {`--ruby-version`, `< 3.0`}}, []string{`system`}},
+	}
+	for _, tt := range tests {
+		var sel rubySelector
+		for _, o := range tt.opts {
+			if err := sel.setOption(o[0], o[1]); err != nil {
+				t.Fatalf("rubySelector.setOption() returned error for `%v`: %v", o, err)
+			}
+		}
+
+		tagHashes, err := selectRubies(ctx, &sel)
+		if err != nil {
+			t.Errorf("selectRubies() returned error for `%v`: %v", tt.opts, err)
+			continue
+		}
+		var got []string
+		for _, h := range tagHashes {
+			got = append(got, ctx.Registry.Rubies[h].TagLabel)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectRubies() incorrect selection for `%v`\n  want: `%v`\n  got: `%v`",
+				tt.opts, tt.want, got)
+		}
+	}
+
+	var sel rubySelector
+	sel.setOption(`--engine`, `rbx`)
+	if _, err := selectRubies(ctx, &sel); err == nil {
+		t.Error("selectRubies() should return error when no rubies match")
+	}
+	if err := sel.setOption(`--order`, `random`); err == nil {
+		t.Error("rubySelector.setOption() should return error for invalid order")
+	}
+}
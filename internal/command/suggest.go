@@ -0,0 +1,173 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+// suggest returns the candidates similar enough to a mistyped word to be
+// suggested as a replacement, ordered from most to least similar. Words of up
+// to 3 chars may differ from a candidate by a single edit, and longer words
+// by two edits, where swapping adjacent chars is a single edit.
+func suggest(word string, candidates []string) []string {
+	maxDist := 2
+	if len(word) <= 3 {
+		maxDist = 1
+	}
+
+	dists := make(map[string]int)
+	for _, c := range candidates {
+		if c == `` || c == word {
+			continue
+		}
+		if d := editDistance(strings.ToLower(word), strings.ToLower(c)); d <= maxDist && d < len(word) {
+			if prev, ok := dists[c]; !ok || d < prev {
+				dists[c] = d
+			}
+		}
+	}
+
+	var matches []string
+	for c := range dists {
+		matches = append(matches, c)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if dists[matches[i]] != dists[matches[j]] {
+			return dists[matches[i]] < dists[matches[j]]
+		}
+		return matches[i] < matches[j]
+	})
+
+	return matches
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// i.e. the number of char insertions, deletions, substitutions and adjacent
+// char transpositions needed to turn a into b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(s)][len(t)]
+}
+
+func minInt(n int, rest ...int) int {
+	for _, m := range rest {
+		if m < n {
+			n = m
+		}
+	}
+	return n
+}
+
+// suggestCommands returns the names of the router's commands having a name
+// or alias similar to a mistyped command.
+func (r *Router) suggestCommands(cmd string) []string {
+	var names []string
+	byAlias := make(map[string]string)
+	for alias, c := range r.handlers {
+		if c.Hidden {
+			continue
+		}
+		names = append(names, alias)
+		byAlias[alias] = c.Name
+	}
+
+	return uniqueNames(suggest(cmd, names), byAlias)
+}
+
+// suggestTags returns the tag labels, or IDs, of the registered rubies similar
+// to a mistyped tag label.
+func suggestTags(ctx *env.Context, label string) []string {
+	var names []string
+	for _, ri := range ctx.Registry.Rubies {
+		names = append(names, ri.TagLabel, ri.ID)
+	}
+
+	return suggest(label, names)
+}
+
+// suggestTopLevel returns the commands, plugins, user aliases and registered
+// ruby tags similar to a mistyped top-level command or tag.
+func suggestTopLevel(ctx *env.Context, cmd string) []string {
+	var names []string
+	for _, p := range discoverPlugins(ctx) {
+		names = append(names, p.Name)
+	}
+	for _, a := range aliasCommands(ctx) {
+		names = append(names, a.Name)
+	}
+
+	matches := append(CmdRouter.suggestCommands(cmd), suggest(cmd, names)...)
+	return uniqueNames(append(matches, suggestTags(ctx, cmd)...), nil)
+}
+
+// uniqueNames maps the matches to their canonical names, if any, dropping
+// duplicates while keeping the order of the matches.
+func uniqueNames(matches []string, canonical map[string]string) (names []string) {
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if n, ok := canonical[m]; ok {
+			m = n
+		}
+		if !seen[m] {
+			seen[m] = true
+			names = append(names, m)
+		}
+	}
+
+	return
+}
+
+// didYouMean returns a `; did you mean ...?` hint listing up to three
+// suggestions, or an empty string if there are none.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ``
+	}
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = fmt.Sprintf("`%s`", s)
+	}
+	if n := len(quoted); n > 1 {
+		return fmt.Sprintf("; did you mean %s or %s?", strings.Join(quoted[:n-1], `, `), quoted[n-1])
+	}
+	return fmt.Sprintf("; did you mean %s?", quoted[0])
+}
+
+// noRubyError returns the error for a tag label matching no registered ruby,
+// suggesting similar tag labels.
+func noRubyError(ctx *env.Context, label string) error {
+	return fmt.Errorf("---> unable to find registered ruby matching `%s`%s", label,
+		didYouMean(suggestTags(ctx, label)))
+}
@@ -0,0 +1,128 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"bitbucket.org/jonforums/uru/internal/env"
+)
+
+func TestEditDistance(t *testing.T) {
+	var tests = []struct {
+		a, b string
+		want int
+	}{
+		{``, ``, 0},
+		{`ls`, ``, 2},
+		{`lsit`, `list`, 1},
+		{`admn`, `admin`, 1},
+		{`refesh`, `refresh`, 1},
+		{`322p35`, `322p53`, 1},
+		{`gemest`, `gems`, 2},
+		{`kitten`, `sitting`, 3},
+	}
+
+	for _, v := range tests {
+		if got := editDistance(v.a, v.b); got != v.want {
+			t.Errorf("editDistance(%q, %q) not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.a, v.b, v.want, got)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{`ls`, `list`, `gem`, `gems`, `gemset`, `version`, `ver`}
+
+	var tests = []struct {
+		word string
+		want []string
+	}{
+		{`lsit`, []string{`list`, `ls`}},
+		{`gemest`, []string{`gemset`, `gems`}},
+		{`VERISON`, []string{`version`}},
+		{`x`, nil},
+		{`ls`, nil},
+		{`rubyist`, nil},
+	}
+
+	for _, v := range tests {
+		if got := suggest(v.word, candidates); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("suggest(%q) not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.word, v.want, got)
+		}
+	}
+}
+
+func TestSuggestCommands(t *testing.T) {
+	r := NewRouter(nil)
+	r.Handle([]string{`ls`, `list`}, &Command{Name: `ls`})
+	r.Handle([]string{`refresh`}, &Command{Name: `refresh`})
+	r.Handle([]string{`__secret`}, &Command{Name: `__secret`, Hidden: true})
+
+	var tests = []struct {
+		cmd  string
+		want []string
+	}{
+		{`lsit`, []string{`ls`}},
+		{`refesh`, []string{`refresh`}},
+		{`__secrt`, nil},
+	}
+
+	for _, v := range tests {
+		if got := r.suggestCommands(v.cmd); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("Router.suggestCommands(%q) not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.cmd, v.want, got)
+		}
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	var tests = []struct {
+		suggestions []string
+		want        string
+	}{
+		{nil, ``},
+		{[]string{`ls`}, "; did you mean `ls`?"},
+		{[]string{`help`, `hello`}, "; did you mean `help` or `hello`?"},
+		{[]string{`a`, `b`, `c`, `d`}, "; did you mean `a`, `b` or `c`?"},
+	}
+
+	for _, v := range tests {
+		if got := didYouMean(v.suggestions); got != v.want {
+			t.Errorf("didYouMean(%v) not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.suggestions, v.want, got)
+		}
+	}
+}
+
+func TestMistypedCommandSuggestions(t *testing.T) {
+	ctx := env.NewContext()
+	ctx.SetHome(`no-such-uru-home`)
+	ctx.Registry.Rubies = env.RubyMap{
+		`3574260220`: env.Ruby{ID: `2.2.3-p146`, TagLabel: `223p146`},
+	}
+
+	var tests = []struct {
+		args []string
+		want string
+	}{
+		{[]string{`lsit`}, "no ruby or command `lsit`; did you mean `ls`?"},
+		{[]string{`223p164`}, "no ruby or command `223p164`; did you mean `223p146`?"},
+		{[]string{`xyzzy`}, "unable to find registered ruby matching `xyzzy`"},
+		{[]string{`admin`, `refesh`}, "---> did you mean `refresh`?"},
+		{[]string{`exec`, `223p164`, `--`, `ruby`}, "matching `223p164`; did you mean `223p146`?"},
+	}
+
+	for _, v := range tests {
+		ctx.SetCmdAndArgs(v.args[0], v.args[1:])
+		err := CmdRouter.Dispatch(ctx, v.args[0])
+		if err == nil || !strings.Contains(err.Error(), v.want) {
+			t.Errorf("Dispatch() not suggesting similar names for `%v`\n  want: `%v`\n  got: `%v`",
+				v.args, v.want, err)
+		}
+	}
+}
@@ -4,25 +4,36 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"bitbucket.org/jonforums/uru/internal/env"
 )
 
 var useCmd *Command = &Command{
 	Name:  "TAG",
-	Usage: "TAG",
+	Usage: "TAG[@GEMSET]",
 	Eg:    "223p146",
 	Short: "use ruby identified by TAG, 'auto', or 'nil'",
+	Long: `Switches the calling shell to the registered ruby identified by TAG, which
+may be any part of a ruby's tag label or description, e.g. '32' for a ruby
+tagged as '322p53'. When TAG matches several rubies, uru asks which one to
+use. Adding @gemset also activates the project gemset of the current dir,
+and @NAME the named gemset NAME.
+
+The 'auto' tag uses the ruby named by the nearest .ruby-version file, looking
+in the current dir, its parents and finally the home dir, while 'nil' removes
+the uru ruby from the shell's PATH, restoring any system ruby.`,
 }
 
 func init() {
 	CmdRouter.Handle(useCmd.Aliases, useCmd)
 }
 
-func use(ctx *env.Context) {
-	cmd := ctx.Cmd()
+func use(ctx *env.Context) error {
+	cmd, gemset, _ := parseGemsetName(ctx.Cmd())
 
 	// use .ruby-version file contents to select which ruby to activate
 	var tags env.RubyMap
@@ -31,17 +42,18 @@ func use(ctx *env.Context) {
 	case `auto`:
 		tags, err = useRubyVersionFile(ctx, versionator)
 		if err != nil {
-			fmt.Println("---> unable to find or process a `.ruby-version` file")
-			os.Exit(1)
+			return errors.New("---> unable to find or process a `.ruby-version` file")
 		}
 	case `nil`:
-		useNil(ctx)
-		os.Exit(0)
+		return useNil(ctx)
 	default:
 		tags, err = env.VersionFragmentToTag(ctx, cmd)
 		if err != nil {
-			fmt.Printf("---> unable to find registered ruby matching `%s`\n", cmd)
-			os.Exit(1)
+			// a mistyped command also ends up here
+			if hint := didYouMean(suggestTopLevel(ctx, cmd)); hint != `` {
+				return fmt.Errorf("---> no ruby or command `%s`%s", cmd, hint)
+			}
+			return fmt.Errorf("---> unable to find registered ruby matching `%s`", cmd)
 		}
 	}
 
@@ -55,26 +67,75 @@ func use(ctx *env.Context) {
 	} else {
 		// multiple rubies match the given tag label, ask the user for the
 		// correct one.
-		tagHash, err = env.SelectRubyFromList(tags, cmd, `use`)
+		tagHash, err = env.SelectRubyFromList(ctx, tags, cmd, `use`)
 		if err != nil {
-			os.Exit(1)
+			return selectionError(err)
 		}
 	}
 
+	return activateRuby(ctx, tagHash, gemset)
+}
+
+// activateRuby creates the environment switcher script activating a registered
+// ruby, and optionally a project or named gemset, in the calling shell.
+func activateRuby(ctx *env.Context, tagHash, gemset string) (err error) {
 	newRb := ctx.Registry.Rubies[tagHash]
 
-	newPath, err := env.PathListForTagHash(ctx, tagHash)
+	var newPath []string
+	gemHome, gemPath := newRb.GemHome, ``
+	if gemset == `` {
+		newPath, err = env.PathListForTagHash(ctx, tagHash)
+	} else {
+		gemHome, gemPath, err = gemsetEnv(ctx, newRb, gemset)
+		if err != nil {
+			return err
+		}
+		newPath, err = env.PathListForGemset(ctx, tagHash, gemHome)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "---> unable to use ruby internally known as `%s`\n", tagHash)
-		os.Exit(1)
+		return fmt.Errorf("---> unable to use ruby internally known as `%s`", tagHash)
 	}
 
 	// create the environment switcher script
-	env.CreateSwitcherScript(ctx, &newPath, newRb.GemHome)
+	if _, err = env.CreateSwitcherScript(ctx, &newPath, gemHome, gemPath); err != nil {
+		return fmt.Errorf("[ERROR] %v", err)
+	}
 
 	tagAlias := ``
 	if newRb.TagLabel != `` {
 		tagAlias = fmt.Sprintf("tagged as `%s`", newRb.TagLabel)
 	}
-	fmt.Printf("---> now using %s %s %s\n", newRb.Exe, newRb.ID, tagAlias)
+	switch gemset {
+	case ``:
+	case `gemset`:
+		tagAlias = fmt.Sprintf("%s with project gemset", tagAlias)
+	default:
+		tagAlias = fmt.Sprintf("%s with `%s` gemset", tagAlias, gemset)
+	}
+	ctx.Infof("---> now using %s %s %s\n", newRb.Exe, newRb.ID, tagAlias)
+
+	return nil
+}
+
+// gemsetEnv returns the GEM_HOME and GEM_PATH values that activate an existing
+// project or named gemset for the given ruby. GEM_PATH chains the ruby's
+// default gem home and, via the trailing list separator, RubyGems' own default
+// gem dirs so gems installed with the ruby remain available.
+func gemsetEnv(ctx *env.Context, rb env.Ruby, gemset string) (gemHome, gemPath string, err error) {
+	gemHome, err = gemsetDir(ctx, rb, gemset)
+	if err != nil {
+		return
+	}
+	if fi, e := os.Stat(gemHome); e != nil || !fi.IsDir() {
+		return ``, ``, fmt.Errorf("---> `%s` gemset not initialized for %s %s; try `uru admin gemset init`",
+			gemset, rb.Exe, rb.ID)
+	}
+
+	pth := []string{gemHome}
+	if rb.GemHome != `` {
+		pth = append(pth, rb.GemHome)
+	}
+	gemPath = strings.Join(append(pth, ``), string(os.PathListSeparator))
+
+	return
 }
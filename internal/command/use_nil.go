@@ -6,10 +6,10 @@ package command
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 func useNil(ctx *env.Context) error {
@@ -26,13 +26,14 @@ func useNil(ctx *env.Context) error {
 	}
 
 	// remove uru chunk from the current PATH
-	fmt.Println("---> removing non-system ruby from current environment")
+	ctx.Infof("---> removing non-system ruby from current environment\n")
 	newPath := env.DelUruChunk(uruChunk, path)
-	log.Printf("[DEBUG] new PATH: %s\n", newPath)
+	log.Tracef("new PATH: %s\n", newPath)
 
 	// TODO handle pre-existing "system" GEM_HOME via URU_ORIGINAL_GEM_HOME envar
-	// TODO add better error handling
-	env.CreateSwitcherScript(ctx, &newPath, "")
+	if _, err := env.CreateSwitcherScript(ctx, &newPath, "", ""); err != nil {
+		return fmt.Errorf("[ERROR] %v", err)
+	}
 
 	return nil
 }
@@ -8,13 +8,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
 	"bitbucket.org/jonforums/uru/internal/env"
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 type rbVersionFunc func(ctx *env.Context, dir string) (tags env.RubyMap, err error)
@@ -102,7 +102,7 @@ func versionator(ctx *env.Context, dir string) (tags env.RubyMap, err error) {
 	} else {
 		path = fmt.Sprintf("%s%s.ruby-version", dir, string(os.PathSeparator))
 	}
-	log.Printf("[DEBUG] checking for `%s`\n", path)
+	log.Debugf("checking for `%s`\n", path)
 
 	if _, err = os.Stat(path); err != nil {
 		return nil, err
@@ -114,7 +114,7 @@ func versionator(ctx *env.Context, dir string) (tags env.RubyMap, err error) {
 	}
 
 	rbVer := string(bytes.Trim(b, " \r\n"))
-	log.Printf("[DEBUG] .ruby-version data: %s\n", rbVer)
+	log.Debugf(".ruby-version data: %s\n", rbVer)
 
 	return env.VersionFragmentToTag(ctx, rbVer)
 }
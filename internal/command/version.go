@@ -13,17 +13,47 @@ import (
 var versionCmd *Command = &Command{
 	Name:    "version",
 	Aliases: []string{"ver", "version"},
-	Usage:   "version",
+	Usage:   "version [--format FORMAT]",
 	Eg:      "version",
 	Short:   "display uru version",
-	Run:     version,
+	Long: `Displays uru's version along with the OS, architecture and Go version uru was
+built with. The --format option displays the same as a versioned JSON
+document, tab separated values, or by a Go text/template.`,
+	Flags: []Flag{formatFlag},
+	Run:   version,
 }
 
 func init() {
 	CmdRouter.Handle(versionCmd.Aliases, versionCmd)
 }
 
-func version(ctx *env.Context) {
-	fmt.Printf("%s v%s [%s/%s %s]\n", env.AppName, env.AppVersion,
+func version(ctx *env.Context) error {
+	format, err := parseOutputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	if !format.isDefault() {
+		return format.write(ctx, `version`, []versionRecord{{
+			Name:    env.AppName,
+			Version: env.AppVersion,
+			OS:      runtime.GOOS,
+			Arch:    runtime.GOARCH,
+			Go:      runtime.Version(),
+		}})
+	}
+
+	fmt.Fprintf(ctx.Stdout, "%s v%s [%s/%s %s]\n", env.AppName, env.AppVersion,
 		runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	return nil
+}
+
+// versionRecord is the machine readable form of uru's version displayed by
+// `version --format`. Its fields are part of uru's documented output schema.
+type versionRecord struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Go      string `json:"go"`
 }
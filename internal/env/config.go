@@ -0,0 +1,95 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Config holds the settings of uru's INI style config file indexed by section
+// and key names. A config file looks like
+//
+//    # comment
+//    [alias]
+//    t = exec auto -- rake test
+//
+// where section and key names are case insensitive.
+type Config map[string]map[string]string
+
+// ReadConfig parses an INI style config.
+func ReadConfig(r io.Reader) (Config, error) {
+	cfg := make(Config)
+	section := ``
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == ``, strings.HasPrefix(line, `#`), strings.HasPrefix(line, `;`):
+			continue
+		case strings.HasPrefix(line, `[`) && strings.HasSuffix(line, `]`):
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			if section == `` {
+				return nil, fmt.Errorf("line %d: empty section name", n)
+			}
+			if cfg[section] == nil {
+				cfg[section] = make(map[string]string)
+			}
+			continue
+		}
+
+		kv := strings.SplitN(line, `=`, 2)
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		if len(kv) != 2 || key == `` {
+			return nil, fmt.Errorf("line %d: expected `key = value`", n)
+		}
+		if section == `` {
+			return nil, fmt.Errorf("line %d: `%s` not in a section", n, key)
+		}
+		cfg[section][key] = strings.TrimSpace(kv[1])
+	}
+
+	return cfg, s.Err()
+}
+
+// ReadConfigFile parses the INI style config file. A missing file results in
+// an empty config.
+func ReadConfigFile(path string) (Config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return make(Config), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, err := ReadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Get returns the value of a key in a section and whether the key exists.
+func (c Config) Get(section, key string) (string, bool) {
+	v, ok := c[strings.ToLower(section)][strings.ToLower(key)]
+	return v, ok
+}
+
+// Keys returns the sorted key names of a section.
+func (c Config) Keys(section string) (keys []string) {
+	for k := range c[strings.ToLower(section)] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return
+}
@@ -0,0 +1,60 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	in := `# uru config
+[Alias]
+  t = exec auto -- rake test
+; comment
+Latest=322
+
+[other]
+key = a = b
+`
+	want := Config{
+		`alias`: {`t`: `exec auto -- rake test`, `latest`: `322`},
+		`other`: {`key`: `a = b`},
+	}
+
+	rv, err := ReadConfig(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadConfig() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(rv, want) {
+		t.Errorf("ReadConfig() not returning correct value\n  want: `%v`\n  got: `%v`",
+			want,
+			rv)
+	}
+
+	if v, ok := rv.Get(`ALIAS`, `T`); !ok || v != `exec auto -- rake test` {
+		t.Errorf("Config.Get() not returning correct value\n  want: `%v`\n  got: `%v`",
+			`exec auto -- rake test`,
+			v)
+	}
+	if keys := rv.Keys(`alias`); !reflect.DeepEqual(keys, []string{`latest`, `t`}) {
+		t.Errorf("Config.Keys() not returning correct value\n  want: `%v`\n  got: `%v`",
+			[]string{`latest`, `t`},
+			keys)
+	}
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	for _, in := range []string{
+		"t = ls",
+		"[alias]\njust a line",
+		"[alias]\n = ls",
+		"[ ]\nt = ls",
+	} {
+		if _, err := ReadConfig(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadConfig() not returning an error for %q", in)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrNoInput is returned instead of asking the user for input when the
+	// `--no-input` option was given.
+	ErrNoInput = errors.New("user input required but `--no-input` was given")
+
+	// ErrNotInteractive is returned instead of asking the user to confirm an
+	// operation when stdin isn't a terminal and no one can answer.
+	ErrNotInteractive = errors.New("confirmation required but stdin is not a terminal; give `--yes` to confirm")
+)
+
+// Confirmer asks the user to confirm operations, such as deleting files, that
+// can't be undone. Tests replace a context's confirmer to script the answers.
+type Confirmer interface {
+	// Confirm asks the user a yes or no question, returning true only if the
+	// user answered yes. It returns an error, without asking, if no one can
+	// answer the question.
+	Confirm(prompt string) (yes bool, err error)
+}
+
+// promptConfirmer asks the user to confirm an operation on the context's
+// terminal. The `--yes` option confirms without asking, while the
+// `--no-input` option, or a stdin that isn't a terminal, refuses to ask.
+type promptConfirmer struct {
+	ctx *Context
+}
+
+func (c promptConfirmer) Confirm(prompt string) (bool, error) {
+	ctx := c.ctx
+	switch {
+	case ctx.Options.Yes:
+		return true, nil
+	case ctx.Options.NoInput:
+		return false, ErrNoInput
+	case !ctx.Terminal.IsInputTerminal():
+		return false, ErrNotInteractive
+	}
+
+	var resp string
+	fmt.Fprintf(ctx.Stdout, "%s [Yn] ", prompt)
+	_, err := fmt.Fscanln(ctx.Stdin, &resp)
+	switch {
+	case err == io.EOF:
+		// end of input, e.g. Ctrl-D, is never a yes
+		return false, nil
+	case resp == ``:
+		return true, nil
+	}
+
+	return yResp.MatchString(resp), nil
+}
@@ -0,0 +1,47 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	var tests = []struct {
+		input   string
+		tty     bool
+		yes     bool
+		noInput bool
+		want    bool
+		err     error
+	}{
+		{"\n", true, false, false, true, nil},
+		{"yes\n", true, false, false, true, nil},
+		{"n\n", true, false, false, false, nil},
+		{"", true, false, false, false, nil},
+		{"n\n", true, true, false, true, nil},
+		{"y\n", false, true, false, true, nil},
+		{"y\n", false, false, false, false, ErrNotInteractive},
+		{"y\n", true, false, true, false, ErrNoInput},
+		{"y\n", false, true, true, true, nil},
+	}
+
+	for _, v := range tests {
+		var out bytes.Buffer
+		ctx := NewContext()
+		ctx.Stdin, ctx.Stdout, ctx.Terminal = strings.NewReader(v.input), &out, &fakeTerminal{tty: v.tty}
+		ctx.Options.Yes, ctx.Options.NoInput = v.yes, v.noInput
+
+		rv, err := ctx.Confirm(`OK?`)
+		if rv != v.want || err != v.err {
+			t.Errorf("Confirm() not returning correct value for %q, tty=%v, yes=%v, no-input=%v\n  want: `%v` (%v)\n  got: `%v` (%v)",
+				v.input, v.tty, v.yes, v.noInput, v.want, v.err, rv, err)
+		}
+		if (v.yes || v.err != nil) && out.Len() != 0 {
+			t.Errorf("Confirm() prompting when no answer is needed or possible\n  got: `%v`", out.String())
+		}
+	}
+}
@@ -3,12 +3,45 @@
 
 package env
 
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Options are uru's global command line options. They are given before the
+// command and apply the same way to every command.
+type Options struct {
+	Quiet   bool   // don't display informational `--->` messages
+	NoColor bool   // never colorize output
+	JSON    bool   // display query results as JSON
+	Yes     bool   // answer yes to all confirmation prompts
+	NoInput bool   // fail instead of asking the user for input
+	Shell   string // shell that invoked uru, overriding URU_INVOKER
+}
+
 type Context struct {
 	home        string
 	command     string
 	commandArgs []string
+	flags       map[string][]string
 
 	Registry RubyRegistry
+	Config   Config
+	Options  Options
+
+	// Streams used for all user interaction; a new context uses the process's
+	// standard streams, which tests replace to run commands in-process.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Terminal connected to the standard streams, used for interactive
+	// selections such as picking a ruby.
+	Terminal Terminal
+
+	// Confirmer asking the user to confirm operations that can't be undone.
+	Confirmer Confirmer
 }
 
 func (c *Context) Home() string {
@@ -18,6 +51,19 @@ func (c *Context) SetHome(h string) {
 	c.home = h
 }
 
+// Infof displays an informational message unless the `--quiet` option was
+// given. Errors and warnings are always displayed.
+func (c *Context) Infof(format string, args ...interface{}) {
+	if !c.Options.Quiet {
+		fmt.Fprintf(c.Stdout, format, args...)
+	}
+}
+
+// Confirm asks the user to confirm an operation using the context's Confirmer.
+func (c *Context) Confirm(prompt string) (bool, error) {
+	return c.Confirmer.Confirm(prompt)
+}
+
 func (c *Context) Cmd() string {
 	return c.command
 }
@@ -37,12 +83,47 @@ func (c *Context) SetCmdAndArgs(cmd string, args []string) {
 	c.commandArgs = args
 }
 
+// Flag returns the last value given for the named command line flag, or an
+// empty string if the flag was not given or is a boolean flag.
+func (c *Context) Flag(name string) string {
+	if v := c.flags[name]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ``
+}
+
+// FlagValues returns all values given for the named, possibly repeated, flag.
+func (c *Context) FlagValues(name string) []string {
+	return c.flags[name]
+}
+
+// IsFlagSet indicates whether the named command line flag was given.
+func (c *Context) IsFlagSet(name string) bool {
+	_, ok := c.flags[name]
+	return ok
+}
+func (c *Context) SetFlags(flags map[string][]string) {
+	c.flags = flags
+}
+
 func NewContext() *Context {
-	return &Context{
+	ctx := &Context{
 		Registry: RubyRegistry{
 			Version:    RubyRegistryVersion,
 			Rubies:     make(RubyMap, 4),
 			marshaller: marshalRubies,
 		},
+		Config: make(Config),
+		Options: Options{
+			Shell: os.Getenv(`URU_INVOKER`),
+		},
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+
+		Terminal: stdTerminal{},
 	}
+	ctx.Confirmer = promptConfirmer{ctx}
+
+	return ctx
 }
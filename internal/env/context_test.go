@@ -4,6 +4,7 @@
 package env
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 )
@@ -98,3 +99,46 @@ func TestContextSetCmdAndArgs(t *testing.T) {
 			rv2)
 	}
 }
+
+func TestContextFlags(t *testing.T) {
+	ctx := NewContext()
+
+	if ctx.IsFlagSet(`verbose`) {
+		t.Error("Context.IsFlagSet() not returning false for unset flag")
+	}
+
+	ctx.SetFlags(map[string][]string{`verbose`: {``}, `only`: {`223`, `231`}})
+	if !ctx.IsFlagSet(`verbose`) {
+		t.Error("Context.IsFlagSet() not returning true for boolean flag")
+	}
+	if rv := ctx.Flag(`only`); rv != `231` {
+		t.Errorf("Context.Flag() not returning correct value\n  want: `%s`\n  got: `%s`",
+			`231`,
+			rv)
+	}
+	if rv := ctx.FlagValues(`only`); !reflect.DeepEqual(rv, []string{`223`, `231`}) {
+		t.Errorf("Context.FlagValues() not returning correct value\n  want: `%v`\n  got: `%v`",
+			[]string{`223`, `231`},
+			rv)
+	}
+}
+
+func TestContextInfof(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := NewContext()
+	ctx.Stdout = &buf
+
+	ctx.Infof("---> now using %s\n", `231`)
+	if rv := buf.String(); rv != "---> now using 231\n" {
+		t.Errorf("Context.Infof() not displaying correct message\n  want: `%v`\n  got: `%v`",
+			"---> now using 231\n",
+			rv)
+	}
+
+	buf.Reset()
+	ctx.Options.Quiet = true
+	ctx.Infof("---> now using %s\n", `231`)
+	if rv := buf.String(); rv != `` {
+		t.Errorf("Context.Infof() not silenced by the `--quiet` option\n  got: `%v`", rv)
+	}
+}
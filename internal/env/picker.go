@@ -0,0 +1,274 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Max number of rubies the picker displays at once.
+const pickerRows = 10
+
+// Max width of a picker line; longer lines are truncated so that redrawing the
+// picker never has to account for wrapped lines.
+const pickerWidth = 79
+
+// ErrNoSelection is returned when the user exits a ruby selection without
+// selecting a ruby.
+var ErrNoSelection = errors.New("no ruby selected")
+
+type pickerKey int
+
+const (
+	keyNone pickerKey = iota
+	keyRune
+	keyUp
+	keyDown
+	keyEnter
+	keyBackspace
+	keyClear
+	keyCancel
+)
+
+type pickerItem struct {
+	tagHash string
+	ruby    Ruby
+	text    string // lower case text matched by the filter
+}
+
+// picker is an interactive, type-to-filter list of rubies drawn on a terminal
+// in raw mode.
+type picker struct {
+	prompt  string
+	items   []pickerItem
+	filter  []rune
+	matches []int // indexes of the items matching the filter
+	cursor  int   // index into matches of the highlighted item
+	lines   int   // number of lines drawn by the last render
+}
+
+func newPicker(tags RubyMap, prompt, filter string) (*picker, error) {
+	sortedTagHashes, err := SortTagsByTagLabel(&tags)
+	if err != nil {
+		return nil, errors.New("error: unable to sort matching rubies")
+	}
+
+	p := &picker{prompt: prompt, filter: []rune(filter)}
+	for _, t := range sortedTagHashes {
+		rb := tags[t]
+		p.items = append(p.items, pickerItem{
+			tagHash: t,
+			ruby:    rb,
+			text:    strings.ToLower(strings.Join([]string{rb.TagLabel, rb.Description, rb.Home}, ` `)),
+		})
+	}
+	p.match()
+
+	return p, nil
+}
+
+// match updates the items matching the filter. An item matches when each of
+// the filter's space separated terms is found in its tag label, description or
+// home, or when the term's chars appear in order in its tag label, e.g. `322`
+// matches the `3.2.2-p53` ruby tagged as `322p53`. Items containing all terms
+// are listed before those only matching in order.
+func (p *picker) match() {
+	terms := strings.Fields(strings.ToLower(string(p.filter)))
+	var exact, fuzzy []int
+	for i, it := range p.items {
+		isExact, ok := true, true
+		for _, term := range terms {
+			if strings.Contains(it.text, term) {
+				continue
+			}
+			isExact = false
+			if !isSubsequence(term, strings.ToLower(it.ruby.TagLabel)) {
+				ok = false
+				break
+			}
+		}
+		switch {
+		case ok && isExact:
+			exact = append(exact, i)
+		case ok:
+			fuzzy = append(fuzzy, i)
+		}
+	}
+	p.matches = append(exact, fuzzy...)
+	p.cursor = 0
+}
+
+// isSubsequence indicates whether the chars of sub appear in order in s.
+func isSubsequence(sub, s string) bool {
+	r := []rune(sub)
+	i := 0
+	for _, c := range s {
+		if i < len(r) && c == r[i] {
+			i++
+		}
+	}
+	return i == len(r)
+}
+
+// run reads keystrokes from r, redrawing the picker on w after each one, until
+// a ruby is picked or the picker is cancelled.
+func (p *picker) run(r io.Reader, w io.Writer) (tagHash string, err error) {
+	in := bufio.NewReader(r)
+	defer p.erase(w)
+
+	for {
+		p.render(w)
+
+		key, ch, err := readPickerKey(in)
+		if err != nil {
+			return ``, ErrNoSelection
+		}
+		switch key {
+		case keyRune:
+			p.filter = append(p.filter, ch)
+			p.match()
+		case keyBackspace:
+			if len(p.filter) > 0 {
+				p.filter = p.filter[:len(p.filter)-1]
+				p.match()
+			}
+		case keyClear:
+			p.filter = p.filter[:0]
+			p.match()
+		case keyUp:
+			if p.cursor > 0 {
+				p.cursor--
+			}
+		case keyDown:
+			if p.cursor < len(p.matches)-1 {
+				p.cursor++
+			}
+		case keyEnter:
+			if len(p.matches) > 0 {
+				return p.items[p.matches[p.cursor]].tagHash, nil
+			}
+		case keyCancel:
+			return ``, ErrNoSelection
+		}
+	}
+}
+
+// render draws the picker over the lines drawn by the previous render.
+func (p *picker) render(w io.Writer) {
+	var b strings.Builder
+	p.moveToTop(&b)
+
+	lines := []string{
+		p.prompt,
+		fmt.Sprintf("  filter: %s", string(p.filter)),
+	}
+	start := 0
+	if p.cursor >= pickerRows {
+		start = p.cursor - pickerRows + 1
+	}
+	for i := start; i < len(p.matches) && i < start+pickerRows; i++ {
+		it := p.items[p.matches[i]]
+		marker := ` `
+		if i == p.cursor {
+			marker = `>`
+		}
+		desc := it.ruby.Description
+		if len(desc) > 36 {
+			desc = fmt.Sprintf("%.33s...", desc)
+		}
+		lines = append(lines, fmt.Sprintf(" %s %-12.12s: %-36s  %s",
+			marker, it.ruby.TagLabel, desc, it.ruby.Home))
+	}
+	if len(p.matches) == 0 {
+		lines = append(lines, "   (no matching rubies)")
+	}
+	lines = append(lines, "  (type to filter, up/down to move, enter to pick, esc to exit)")
+
+	for _, l := range lines {
+		if r := []rune(l); len(r) > pickerWidth {
+			l = string(r[:pickerWidth-3]) + `...`
+		}
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	p.lines = len(lines)
+
+	io.WriteString(w, b.String())
+}
+
+// erase removes the picker from the terminal.
+func (p *picker) erase(w io.Writer) {
+	var b strings.Builder
+	p.moveToTop(&b)
+	p.lines = 0
+	io.WriteString(w, b.String())
+}
+
+// moveToTop moves the cursor to the first line drawn by the previous render
+// and clears the lines below it.
+func (p *picker) moveToTop(b *strings.Builder) {
+	if p.lines > 0 {
+		fmt.Fprintf(b, "\033[%dA\r\033[J", p.lines)
+	}
+}
+
+// readPickerKey reads a keystroke, returning the char typed for keyRune.
+func readPickerKey(in *bufio.Reader) (key pickerKey, ch rune, err error) {
+	ch, _, err = in.ReadRune()
+	if err != nil {
+		return keyNone, 0, err
+	}
+
+	switch ch {
+	case '\r', '\n':
+		return keyEnter, ch, nil
+	case 0x03, 0x04, 0x07: // Ctrl-C, Ctrl-D, Ctrl-G
+		return keyCancel, ch, nil
+	case 0x08, 0x7f: // Ctrl-H, DEL
+		return keyBackspace, ch, nil
+	case 0x15: // Ctrl-U
+		return keyClear, ch, nil
+	case 0x10: // Ctrl-P
+		return keyUp, ch, nil
+	case 0x0e: // Ctrl-N
+		return keyDown, ch, nil
+	case 0x1b:
+		// A lone ESC cancels; arrow keys send an escape sequence whose
+		// remaining bytes arrive together with the ESC.
+		if in.Buffered() == 0 {
+			return keyCancel, ch, nil
+		}
+		if next, _ := in.Peek(1); next[0] != '[' && next[0] != 'O' {
+			return keyCancel, ch, nil
+		}
+		in.ReadByte()
+		b, err := in.ReadByte()
+		if err != nil {
+			return keyNone, 0, err
+		}
+		switch b {
+		case 'A':
+			return keyUp, ch, nil
+		case 'B':
+			return keyDown, ch, nil
+		}
+		// skip the rest of unsupported sequences such as `ESC [ 3 ~`
+		for b >= '0' && b <= '9' || b == ';' {
+			if b, err = in.ReadByte(); err != nil {
+				return keyNone, 0, err
+			}
+		}
+		return keyNone, ch, nil
+	}
+
+	if unicode.IsPrint(ch) {
+		return keyRune, ch, nil
+	}
+	return keyNone, ch, nil
+}
@@ -0,0 +1,136 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// fakeTerminal is a scripted terminal whose keystrokes are read from the
+// context's Stdin.
+type fakeTerminal struct {
+	tty           bool
+	raw, restored int
+}
+
+func (t *fakeTerminal) IsTerminal() bool      { return t.tty }
+func (t *fakeTerminal) IsInputTerminal() bool { return t.tty }
+
+func (t *fakeTerminal) MakeRaw() (func() error, error) {
+	t.raw++
+	return func() error { t.restored++; return nil }, nil
+}
+
+var pickerRubies = RubyMap{
+	`3574260220`: Ruby{ID: `2.2.3-p146`, TagLabel: `223p146`, Description: `ruby 2.2.3p146`, Home: `/rubies/ruby-2.2.3/bin`},
+	`1234567890`: Ruby{ID: `2.3.1`, TagLabel: `231`, Description: `ruby 2.3.1p112`, Home: `/rubies/ruby-2.3.1/bin`},
+	`1078060107`: Ruby{ID: `9.4.2`, TagLabel: `942`, Description: `jruby 9.4.2.0 (3.1.0)`, Home: `/rubies/jruby-9.4.2/bin`},
+}
+
+func TestPickRubyTerminal(t *testing.T) {
+	var tests = []struct {
+		script string
+		filter string
+		want   string
+	}{
+		{"\r", ``, `3574260220`},
+		{"\x1b[B\r", ``, `1234567890`},
+		{"\x1b[B\x1b[B\x1b[B\x1b[A\r", ``, `1234567890`},
+		{"\x0e\x0e\x10\n", ``, `1234567890`},
+		{"jruby 9\r", ``, `1078060107`},
+		{"2p4\r", ``, `3574260220`},
+		{"\r", `jruby`, `1078060107`},
+		{"zz\x7f\x7f231\r", ``, `1234567890`},
+		{"942\x15\x1b[B\r", ``, `1234567890`},
+		{"\x1b[3~\x1b[C\r", ``, `3574260220`},
+		{"zzz\r\x1b", ``, ``},
+		{"\x03", ``, ``},
+		{"", ``, ``},
+	}
+
+	for _, v := range tests {
+		var out bytes.Buffer
+		term := &fakeTerminal{tty: true}
+		ctx := NewContext()
+		ctx.Stdin, ctx.Stdout, ctx.Terminal = strings.NewReader(v.script), &out, term
+
+		got, err := PickRuby(ctx, pickerRubies, `registered rubies`, `use`, v.filter)
+		if got != v.want || (v.want == `` && err != ErrNoSelection) {
+			t.Errorf("PickRuby() not returning correct value for script %q\n  want: `%v`\n  got: `%v` (%v)",
+				v.script, v.want, got, err)
+		}
+		if term.raw != 1 || term.restored != 1 {
+			t.Errorf("PickRuby() not restoring the terminal for script %q: raw %d, restored %d",
+				v.script, term.raw, term.restored)
+		}
+		if !strings.Contains(out.String(), "pick one to use") {
+			t.Errorf("PickRuby() not displaying the picker for script %q\n  got: %q", v.script, out.String())
+		}
+	}
+}
+
+func TestPickRubyNumbered(t *testing.T) {
+	var tests = []struct {
+		input  string
+		filter string
+		want   string
+	}{
+		{"2\n", ``, `1234567890`},
+		{"4\nabc\n3\n", ``, `1078060107`},
+		{"1\n", `2.3.1`, `1234567890`},
+		{"\n", ``, ``},
+		{"0\n", ``, ``},
+		{"", ``, ``},
+	}
+
+	for _, v := range tests {
+		var out bytes.Buffer
+		term := &fakeTerminal{}
+		ctx := NewContext()
+		ctx.Stdin, ctx.Stdout, ctx.Terminal = strings.NewReader(v.input), &out, term
+
+		got, err := PickRuby(ctx, pickerRubies, `registered rubies`, `use`, v.filter)
+		if got != v.want || (v.want == `` && err != ErrNoSelection) {
+			t.Errorf("PickRuby() not returning correct value for input %q\n  want: `%v`\n  got: `%v` (%v)",
+				v.input, v.want, got, err)
+		}
+		if term.raw != 0 {
+			t.Errorf("PickRuby() switching a non-terminal to raw mode for input %q", v.input)
+		}
+	}
+
+	ctx := NewContext()
+	ctx.Stdin, ctx.Stdout, ctx.Terminal = strings.NewReader("1\n"), &bytes.Buffer{}, &fakeTerminal{}
+	if _, err := PickRuby(ctx, pickerRubies, `registered rubies`, `use`, `rbx`); err == nil || err == ErrNoSelection {
+		t.Errorf("PickRuby() not returning error for a filter matching no rubies\n  got: `%v`", err)
+	}
+
+	ctx.Stdin, ctx.Terminal = strings.NewReader("1\n"), &fakeTerminal{tty: true}
+	ctx.Options.NoInput = true
+	if _, err := PickRuby(ctx, pickerRubies, `registered rubies`, `use`, ``); err != ErrNoInput {
+		t.Errorf("PickRuby() not failing when given `--no-input`\n  got: `%v`", err)
+	}
+}
+
+func TestPickerRender(t *testing.T) {
+	var out bytes.Buffer
+	p, _ := newPicker(pickerRubies, `---> pick`, `23`)
+
+	p.render(&out)
+	want := "---> pick\n  filter: 23\n" +
+		" > 223p146     : ruby 2.2.3p146                        /rubies/ruby-2.2.3/bin\n" +
+		"   231         : ruby 2.3.1p112                        /rubies/ruby-2.3.1/bin\n" +
+		"  (type to filter, up/down to move, enter to pick, esc to exit)\n"
+	if got := out.String(); got != want {
+		t.Errorf("picker.render() not drawing correct lines\n  want: %q\n  got: %q", want, got)
+	}
+
+	out.Reset()
+	p.render(&out)
+	if got := out.String(); !strings.HasPrefix(got, "\033[5A\r\033[J---> pick\n") {
+		t.Errorf("picker.render() not redrawing over the previous lines\n  got: %q", got)
+	}
+}
@@ -8,13 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 const (
@@ -93,7 +94,7 @@ func CurrentRubyInfo(ctx *Context) (tagHash string, info Ruby, err error) {
 		err = errors.New("Unable to read PATH envar value")
 		return
 	}
-	log.Printf("[DEBUG] CurrentRubyInfo's PATH: %q\n", path)
+	log.Tracef("CurrentRubyInfo's PATH: %q\n", path)
 
 	uruChunk, ok := GetUruChunk(path)
 	if ok == true {
@@ -102,20 +103,16 @@ func CurrentRubyInfo(ctx *Context) (tagHash string, info Ruby, err error) {
 		//
 		// The uru chunk has the format
 		//
-		//     canary[0]:[GEM_HOME_BIN_DIR]:RUBY_BIN_DIR:canary[1]
+		//     canary[0]:[GEM_HOME_BIN_DIR...]:RUBY_BIN_DIR:canary[1]
+		//
+		// where an activated gemset contributes both its own and the ruby's
+		// default GEM_HOME_BIN_DIR.
 		paths := strings.Split(uruChunk, string(os.PathListSeparator))
-		var curRbPath string
-		switch len(paths) {
-		case 4:
-			// scenario: canary[0]:GEM_HOME_BIN_DIR:RUBY_BIN_DIR:canary[1]
-			curRbPath = paths[2]
-		case 3:
-			// scenario: canary[0]:RUBY_BIN_DIR:canary[1]
-			curRbPath = paths[1]
-		default:
+		if len(paths) < 3 {
 			err = errors.New("Invalid uru chunk")
 			return
 		}
+		curRbPath := paths[len(paths)-2]
 		// Get metadata for currently active ruby
 		sep := string(os.PathSeparator)
 		for _, v := range KnownRubies {
@@ -162,8 +159,10 @@ func RubyInfo(ctx *Context, ruby string) (tagHash string, info Ruby, err error)
 	info.Home = filepath.Dir(rb)
 
 	c := exec.Command(rb, `--version`)
+	log.Debug("spawn", "exe", rb, "args", c.Args[1:])
 	b, err := c.Output()
 	if err != nil {
+		log.Warn("ruby version query failed", "exe", rb, "err", err)
 		err = errors.New("unable to capture ruby version info")
 		return
 	}
@@ -189,15 +188,15 @@ func RubyInfo(ctx *Context, ruby string) (tagHash string, info Ruby, err error)
 		info.TagLabel = strings.Replace(strings.Replace(info.ID, `.`, ``, -1), `-`, ``, -1)
 		tagHash, err = NewTag(ctx, info)
 		if err != nil {
-			// TODO implement
-			panic("unable to create new tag for ruby")
+			err = fmt.Errorf("unable to create new tag for ruby: %v", err)
+			return
 		}
 		info.GemHome = gemHome(info)
 	} else {
 		err = errors.New("unable to parse ruby name and version info")
 		return
 	}
-	log.Printf("[DEBUG] tag hash: %s, %+v\n", tagHash, info)
+	log.Debug("ruby info", "tag", tagHash, "label", info.TagLabel, "exe", info.Exe, "id", info.ID, "home", info.Home)
 
 	return
 }
@@ -210,18 +209,18 @@ func marshalRubies(ctx *Context) (err error) {
 	// TODO extract backup functionality to a utility function
 	_, err = os.Stat(src)
 	if err == nil {
-		log.Printf("[DEBUG] backing up JSON ruby registry\n")
+		log.Debug("registry backup", "src", src, "dst", dst)
 		_, e := CopyFile(dst, src)
 		if e != nil {
-			log.Println("[DEBUG] unable to backup JSON ruby registry")
+			log.Error("registry backup failed", "src", src, "err", e)
 			return e
 		}
 	}
 	if os.IsNotExist(err) {
-		log.Printf("[DEBUG] %s does not exist; creating\n", src)
+		log.Debugf("%s does not exist; creating\n", src)
 		f, e := os.Create(src)
 		if e != nil {
-			log.Printf("[DEBUG] unable to create new %s\n", src)
+			log.Error("registry create failed", "file", src, "err", e)
 			return e
 		}
 		defer f.Close()
@@ -229,7 +228,7 @@ func marshalRubies(ctx *Context) (err error) {
 
 	b, err := json.MarshalIndent(ctx.Registry, ``, `  `)
 	if err != nil {
-		log.Println("[DEBUG] unable to marshall the ruby registry to JSON")
+		log.Error("registry marshal failed", "err", err)
 		return
 	}
 
@@ -237,9 +236,10 @@ func marshalRubies(ctx *Context) (err error) {
 	if err != nil {
 		os.Remove(src)
 		os.Rename(dst, src)
-		log.Println("[DEBUG] unable to persist the updated JSON ruby registry")
+		log.Error("registry write failed", "file", src, "err", err)
 		return
 	}
+	log.Debug("registry write", "file", src, "rubies", len(ctx.Registry.Rubies), "bytes", len(b))
 
 	os.Remove(dst)
 	return
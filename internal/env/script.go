@@ -4,12 +4,14 @@
 package env
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 // switcher script templates
@@ -17,13 +19,11 @@ var batScript = `@ECHO OFF
 REM autogenerated by uru
 
 SET "PATH=%s"
-SET "GEM_HOME=%s"
 `
 
 var ps1Script = `# autogenerated by uru
 
 $env:PATH = "%s"
-$env:GEM_HOME = "%s"
 `
 
 var bashScript = `# autogenerated by uru
@@ -35,10 +35,19 @@ var fishScript = `# autogenerated by uru
 set -gx PATH %s ^/dev/null
 `
 
+// Env vars recording the GEM_PATH set by uru when activating a gemset, and the
+// user's own GEM_PATH it replaced.
+const (
+	uruGemPathVar   = `URU_GEM_PATH`
+	savedGemPathVar = `URU_SAVED_GEM_PATH`
+)
+
 // CreateSwitcherScript creates an environment switcher script customized to the
-// type of shell calling the uru runtime.
-func CreateSwitcherScript(ctx *Context, path *[]string, gemHome string) (scriptName string, err error) {
-	scriptType := os.Getenv(`URU_INVOKER`)
+// type of shell calling the uru runtime. An empty gemHome unsets GEM_HOME. An
+// empty gemPath restores the GEM_PATH replaced by a previously activated
+// gemset, leaving a GEM_PATH set by the user untouched.
+func CreateSwitcherScript(ctx *Context, path *[]string, gemHome, gemPath string) (scriptName string, err error) {
+	scriptType := ctx.Options.Shell
 
 	sep := string(os.PathListSeparator)
 	script := ``
@@ -57,14 +66,14 @@ func CreateSwitcherScript(ctx *Context, path *[]string, gemHome string) (scriptN
 		scriptName = "uru_lackee.fish"
 		sep = " "
 	default:
-		panic("uru invoked from unknown shell (check URU_INVOKER env var)")
+		return ``, errors.New("uru invoked from unknown shell (check URU_INVOKER env var or --shell option)")
 	}
-	log.Printf("[DEBUG] switcher script: %s\n", scriptName)
+	log.Debugf("switcher script: %s\n", scriptName)
 
 	switcher := filepath.Join(ctx.Home(), scriptName)
 	f, err := os.Create(switcher)
 	if err != nil {
-		panic(fmt.Sprintf("unable to create `%s` switcher script", switcher))
+		return ``, fmt.Errorf("unable to create `%s` switcher script", switcher)
 	}
 	defer f.Close()
 
@@ -72,47 +81,75 @@ func CreateSwitcherScript(ctx *Context, path *[]string, gemHome string) (scriptN
 		f.Chmod(0755)
 	}
 
-	content := ``
-	if scriptType == `bash` || scriptType == `fish` {
-		if gemHome != `` {
-			switch scriptType {
-			case "bash":
-				script = strings.Join([]string{script, "export GEM_HOME=%s\n"}, ``)
-			case "fish":
-				script = strings.Join([]string{script, "set -gx GEM_HOME %s\n"}, ``)
-			}
-			content = fmt.Sprintf(script, strings.Join(*path, sep), gemHome)
-		} else {
-			// modify the bash script to suppress GEM_HOME creation when nonexistent
-			switch scriptType {
-			case "bash":
-				script = strings.Join([]string{script, "unset GEM_HOME\n"}, ``)
-			case "fish":
-				script = strings.Join([]string{script, "set -e GEM_HOME\n"}, ``)
-			}
-
-			// morph PATH on bash-like and fish environments to *nix style
-			if runtime.GOOS == `windows` {
-				if scriptType != `fish` {
-					sep = `:`
-				}
-				*path = winPathToNix(path)
-			}
-			content = fmt.Sprintf(script, strings.Join(*path, sep))
+	// morph PATH on bash-like and fish environments to *nix style
+	if runtime.GOOS == `windows` && (scriptType == `bash` || scriptType == `fish`) {
+		if scriptType != `fish` {
+			sep = `:`
 		}
-	} else {
-		content = fmt.Sprintf(script, strings.Join(*path, sep), gemHome)
+		*path = winPathToNix(path)
+	}
+	content := fmt.Sprintf(script, strings.Join(*path, sep))
+	content += shellEnvVar(scriptType, `GEM_HOME`, gemHome)
+	for _, v := range gemPathEnv(gemPath) {
+		content += shellEnvVar(scriptType, v[0], v[1])
 	}
-	log.Printf("[DEBUG] === CreateSwitcherScript content ===\n%#v\n", content)
+	log.Tracef("=== CreateSwitcherScript content ===\n%#v\n", content)
 
 	_, err = f.WriteString(content)
 	if err != nil {
-		panic(fmt.Sprintf("failed to write `%s` switcher script", switcher))
+		// don't leave a partial script for the shell wrapper to run
+		f.Close()
+		os.Remove(switcher)
+		return ``, fmt.Errorf("failed to write `%s` switcher script", switcher)
 	}
 
 	return
 }
 
+// gemPathEnv returns the names and values of the env vars, in order, that set
+// GEM_PATH to gemPath. The user's own GEM_PATH is saved before uru first
+// replaces it, and restored when gemPath is empty. A GEM_PATH the user changed
+// after uru set it is never touched when gemPath is empty.
+func gemPathEnv(gemPath string) (vars [][2]string) {
+	current := os.Getenv(`GEM_PATH`)
+	uruOwned := current != `` && current == os.Getenv(uruGemPathVar)
+
+	if gemPath == `` {
+		if !uruOwned {
+			return nil
+		}
+		return [][2]string{
+			{`GEM_PATH`, os.Getenv(savedGemPathVar)},
+			{savedGemPathVar, ``},
+			{uruGemPathVar, ``},
+		}
+	}
+
+	if !uruOwned {
+		vars = append(vars, [2]string{savedGemPathVar, current})
+	}
+	return append(vars, [2]string{`GEM_PATH`, gemPath}, [2]string{uruGemPathVar, gemPath})
+}
+
+// shellEnvVar returns the shell statement that sets the named env var, or
+// unsets it when the value is empty.
+func shellEnvVar(shell, name, value string) string {
+	switch {
+	case shell == `batch`:
+		return fmt.Sprintf("SET \"%s=%s\"\n", name, value)
+	case shell == `powershell`:
+		return fmt.Sprintf("$env:%s = \"%s\"\n", name, value)
+	case shell == `fish` && value == ``:
+		return fmt.Sprintf("set -e %s\n", name)
+	case shell == `fish`:
+		return fmt.Sprintf("set -gx %s %s\n", name, value)
+	case value == ``:
+		return fmt.Sprintf("unset %s\n", name)
+	}
+
+	return fmt.Sprintf("export %s=%s\n", name, value)
+}
+
 // winPathToNix converts a slice of Windows formatted absolute file system
 // path strings to a slice of *nix style path strings usable by cygwin
 // based shells such as MSYS2 bash on Windows systems.
@@ -153,7 +190,7 @@ func winPathToNix(path *[]string) (nix []string) {
 
 		nix = append(nix, strings.Join(parts, ``))
 	}
-	log.Printf("[DEBUG] === winPathToNix path list ===\n%#v\n", nix)
+	log.Tracef("=== winPathToNix path list ===\n%#v\n", nix)
 
 	return
 }
@@ -4,17 +4,12 @@
 package env
 
 import (
-	"io/ioutil"
-	"log"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
 
-func init() {
-	// silence any logging done in the package files
-	log.SetOutput(ioutil.Discard)
-}
-
 func TestWinPathList2Nix(t *testing.T) {
 	pth := []string{canary[0], `C:\Apps\rubies\ruby-2.1.0\bin`, canary[1], `C:\some\fake\path`}
 	result := strings.Join(winPathToNix(&pth), `:`)
@@ -32,3 +27,74 @@ func TestWinPathList2Nix(t *testing.T) {
 		t.Errorf("Generated *nix path missing `%s` stop canary", canary[1])
 	}
 }
+
+func TestShellEnvVar(t *testing.T) {
+	var tests = []struct {
+		shell, name, value string
+		want               string
+	}{
+		{`bash`, `GEM_HOME`, `/home/u/.gem`, "export GEM_HOME=/home/u/.gem\n"},
+		{`bash`, `GEM_PATH`, ``, "unset GEM_PATH\n"},
+		{`fish`, `GEM_HOME`, `/home/u/.gem`, "set -gx GEM_HOME /home/u/.gem\n"},
+		{`fish`, `GEM_PATH`, ``, "set -e GEM_PATH\n"},
+		{`batch`, `GEM_PATH`, `C:\gems;`, "SET \"GEM_PATH=C:\\gems;\"\n"},
+		{`powershell`, `GEM_PATH`, ``, "$env:GEM_PATH = \"\"\n"},
+	}
+
+	for _, v := range tests {
+		if got := shellEnvVar(v.shell, v.name, v.value); got != v.want {
+			t.Errorf("shellEnvVar() not returning correct value\n  want: `%v`\n  got: `%v`",
+				v.want, got)
+		}
+	}
+}
+
+func TestGemPathEnv(t *testing.T) {
+	var tests = []struct {
+		gemPath, current, uruSet, saved string
+		want                            [][2]string
+	}{
+		// user's GEM_PATH left alone without a gemset
+		{``, `/home/u/gems`, ``, ``, nil},
+		{``, ``, ``, ``, nil},
+		// user changed GEM_PATH after uru activated a gemset
+		{``, `/home/u/gems`, `/gemsets/rails:`, ``, nil},
+		// user's GEM_PATH restored after a gemset
+		{``, `/gemsets/rails:`, `/gemsets/rails:`, `/home/u/gems`,
+			[][2]string{{`GEM_PATH`, `/home/u/gems`}, {savedGemPathVar, ``}, {uruGemPathVar, ``}}},
+		// user's GEM_PATH saved when activating a gemset
+		{`/gemsets/rails:`, `/home/u/gems`, ``, ``,
+			[][2]string{{savedGemPathVar, `/home/u/gems`}, {`GEM_PATH`, `/gemsets/rails:`}, {uruGemPathVar, `/gemsets/rails:`}}},
+		// switching gemsets keeps the saved GEM_PATH
+		{`/gemsets/web:`, `/gemsets/rails:`, `/gemsets/rails:`, `/home/u/gems`,
+			[][2]string{{`GEM_PATH`, `/gemsets/web:`}, {uruGemPathVar, `/gemsets/web:`}}},
+	}
+
+	for _, v := range tests {
+		t.Setenv(`GEM_PATH`, v.current)
+		t.Setenv(uruGemPathVar, v.uruSet)
+		t.Setenv(savedGemPathVar, v.saved)
+
+		if got := gemPathEnv(v.gemPath); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("gemPathEnv() not returning correct value for `%s` with GEM_PATH `%s`\n  want: `%v`\n  got: `%v`",
+				v.gemPath, v.current, v.want, got)
+		}
+	}
+}
+
+func TestCreateSwitcherScriptErrors(t *testing.T) {
+	ctx := NewContext()
+	ctx.SetHome(t.TempDir())
+	path := []string{`/rubies/ruby-2.1/bin`}
+
+	ctx.Options.Shell = `csh`
+	if _, err := CreateSwitcherScript(ctx, &path, ``, ``); err == nil {
+		t.Error("CreateSwitcherScript() not returning an error for an unknown shell")
+	}
+
+	ctx.Options.Shell = `bash`
+	ctx.SetHome(filepath.Join(ctx.Home(), `missing`))
+	if _, err := CreateSwitcherScript(ctx, &path, ``, ``); err == nil {
+		t.Error("CreateSwitcherScript() not returning an error for a missing uru home")
+	}
+}
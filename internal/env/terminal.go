@@ -0,0 +1,47 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"os"
+)
+
+// Terminal is the terminal uru interacts with, e.g. when picking a ruby.
+// Tests replace a context's terminal with a scripted fake terminal reading
+// keystrokes from the context's Stdin.
+type Terminal interface {
+	// IsTerminal indicates whether both stdin and stdout are a terminal.
+	IsTerminal() bool
+
+	// IsInputTerminal indicates whether stdin is a terminal, i.e. whether
+	// someone can answer a prompt.
+	IsInputTerminal() bool
+
+	// MakeRaw switches the terminal to raw mode, reading unechoed keystrokes
+	// without waiting for a line break, and returns a func restoring the
+	// terminal's original mode.
+	MakeRaw() (restore func() error, err error)
+}
+
+// stdTerminal is the terminal connected to the process's standard streams.
+type stdTerminal struct{}
+
+func (stdTerminal) IsTerminal() bool {
+	return isCharDevice(os.Stdin) && isCharDevice(os.Stdout)
+}
+
+func (stdTerminal) IsInputTerminal() bool {
+	return isCharDevice(os.Stdin)
+}
+
+// isCharDevice indicates whether the file is a char device other than the null
+// device, which is how cron jobs and CI systems commonly provide no input.
+func isCharDevice(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
@@ -0,0 +1,36 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+// +build !windows
+
+package env
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// MakeRaw uses stty to disable line buffering, echo and signal generation so
+// that keystrokes such as arrow keys and Ctrl-C are read as they're typed.
+func (stdTerminal) MakeRaw() (restore func() error, err error) {
+	saved, err := stty(`-g`)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stty(`-icanon`, `-echo`, `-isig`, `min`, `1`, `time`, `0`); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		_, err := stty(strings.TrimSpace(saved))
+		return err
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	c := exec.Command(`stty`, args...)
+	c.Stdin = os.Stdin
+	out, err := c.Output()
+	return string(out), err
+}
@@ -0,0 +1,14 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package env
+
+import (
+	"errors"
+)
+
+// MakeRaw is unsupported on Windows consoles, so the ruby picker falls back
+// to a numbered list prompt.
+func (stdTerminal) MakeRaw() (restore func() error, err error) {
+	return nil, errors.New("raw terminal mode not supported on Windows")
+}
@@ -4,11 +4,11 @@
 package env
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"runtime"
+	"strconv"
 )
 
 const (
@@ -29,55 +29,71 @@ func init() {
 	}
 }
 
-// UIYesConfirm asks the user a question and returns Y or N with
-// Y being the default.
-func UIYesConfirm(prompt string) (resp string, err error) {
-	resp = "Y"
-	fmt.Printf("%s [Yn] ", prompt)
-	_, err = fmt.Scanln(&resp)
-	if yResp.MatchString(resp) {
-		resp, err = "Y", nil
-	} else {
-		resp = "N"
-	}
-
-	return
+// SelectRubyFromList asks the user to select one of the registered rubies
+// matching a tag label. It returns the internal identifying tag hash for the
+// selected ruby, or an error if unable to get the users selection.
+func SelectRubyFromList(ctx *Context, tags RubyMap, label, verb string) (tagHash string, err error) {
+	return PickRuby(ctx, tags, fmt.Sprintf("these rubies match your `%s` tag", label), verb, ``)
 }
 
-// SelectRubyFromList presents a list of registered rubies and asks the user to
-// select one. It returns the internal identifying tag hash for the selected ruby,
-// or an error if unable to get the users selection.
-func SelectRubyFromList(tags RubyMap, label, verb string) (tagHash string, err error) {
-	var i, choice uint8
-	choices := make(map[uint8]string)
-	indent := fmt.Sprintf("%19.19s", ``)
+// PickRuby asks the user to pick one of the given rubies in order to act on it
+// as described by verb, e.g. `use`. When both stdin and stdout are terminals
+// the user picks from an interactive list filtered by typing, and initially
+// filtered by filter. Otherwise, the user selects a numbered ruby from a list
+// of the rubies matching filter. The `--no-input` option fails without asking.
+func PickRuby(ctx *Context, tags RubyMap, title, verb, filter string) (tagHash string, err error) {
+	if ctx.Options.NoInput {
+		return ``, ErrNoInput
+	}
+	if ctx.Terminal.IsTerminal() {
+		if restore, e := ctx.Terminal.MakeRaw(); e == nil {
+			defer restore()
+			p, err := newPicker(tags, fmt.Sprintf("---> %s; pick one to %s:", title, verb), filter)
+			if err != nil {
+				return ``, err
+			}
+			return p.run(ctx.Stdin, ctx.Stdout)
+		}
+	}
 
-	// sort the tags by user provided TagLabel to enable stable iteration
-	// display of matching rubies
-	sortedTagHashes, err := SortTagsByTagLabel(&tags)
+	p, err := newPicker(tags, ``, filter)
 	if err != nil {
-		return ``, errors.New("error: unable to sort matching rubies")
+		return ``, err
+	}
+	return selectNumberedRuby(ctx, p, title, verb)
+}
+
+// selectNumberedRuby presents a numbered list of the rubies matching the
+// picker's filter and asks the user to select one, asking again after an
+// invalid response.
+func selectNumberedRuby(ctx *Context, p *picker, title, verb string) (tagHash string, err error) {
+	if len(p.matches) == 0 {
+		return ``, fmt.Errorf("---> no rubies match `%s`", string(p.filter))
 	}
+	indent := fmt.Sprintf("%19.19s", ``)
 
-	// ask user to select from matching rubies
-	fmt.Printf("---> these rubies match your `%s` tag:\n\n", label)
-	for _, k := range sortedTagHashes {
-		i++
-		choices[i] = k
-		fmt.Printf(" [%d] %-12.12s: %s\n%sHome: %s\n",
-			i,
-			tags[k].TagLabel,
-			tags[k].Description,
+	fmt.Fprintf(ctx.Stdout, "---> %s:\n\n", title)
+	for i, m := range p.matches {
+		rb := p.items[m].ruby
+		fmt.Fprintf(ctx.Stdout, " [%d] %-12.12s: %s\n%sHome: %s\n",
+			i+1,
+			rb.TagLabel,
+			rb.Description,
 			indent,
-			tags[k].Home)
-	}
-	fmt.Printf("\nselect [1]-[%d] to %s that specific ruby (0 to exit) [0]: ", i, verb)
-	_, err = fmt.Scanln(&choice)
-	if err != nil || choice == 0 || choice > i {
-		return ``, errors.New("error: unable to get users ruby selection response")
-	} else {
-		tagHash = choices[choice]
+			rb.Home)
 	}
 
-	return
+	for {
+		fmt.Fprintf(ctx.Stdout, "\nselect [1]-[%d] to %s that specific ruby (0 to exit) [0]: ",
+			len(p.matches), verb)
+
+		var resp string
+		if _, err = fmt.Fscanln(ctx.Stdin, &resp); resp == `` || resp == `0` {
+			return ``, ErrNoSelection
+		}
+		if choice, e := strconv.Atoi(resp); e == nil && choice >= 1 && choice <= len(p.matches) {
+			return p.items[p.matches[choice-1]].tagHash, nil
+		}
+		fmt.Fprintf(ctx.Stdout, "---> `%s` is not one of the listed rubies; try again\n", resp)
+	}
 }
@@ -9,12 +9,13 @@ import (
 	"fmt"
 	"hash/fnv"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+
+	"bitbucket.org/jonforums/uru/internal/log"
 )
 
 type tagInfo struct {
@@ -56,7 +57,7 @@ func CopyFile(dst, src string) (written int64, err error) {
 
 	written, err = io.Copy(df, sf)
 
-	log.Printf("[DEBUG] copied file\n  src: %s\n  dst: %s\n  bytes copied: %d\n",
+	log.Debugf("copied file\n  src: %s\n  dst: %s\n  bytes copied: %d\n",
 		src, dst, written)
 
 	return
@@ -161,7 +162,7 @@ func VersionFragmentToTag(ctx *Context, fragment string) (tags RubyMap, err erro
 	if len(tags) == 0 {
 		return nil, errors.New(fmt.Sprintf("---> unable to find ruby matching `%s`\n", fragment))
 	}
-	log.Printf("[DEBUG] tags matching `%s`\n%#v\n", fragment, tags)
+	log.Tracef("tags matching `%s`\n%#v\n", fragment, tags)
 
 	return
 }
@@ -186,7 +187,7 @@ func TagLabelToTag(ctx *Context, label string) (tags RubyMap, err error) {
 	if len(tags) == 0 {
 		return nil, errors.New(fmt.Sprintf("---> unable to find ruby matching `%s`\n", label))
 	}
-	log.Printf("[DEBUG] tags matching `%s`\n%#v\n", label, tags)
+	log.Tracef("tags matching `%s`\n%#v\n", label, tags)
 
 	return
 }
@@ -195,6 +196,17 @@ func TagLabelToTag(ctx *Context, label string) (tags RubyMap, err error) {
 // ruby's tag hash. A tag hash is an uru internal indentifier used for indexing
 // a user's registered rubies.
 func PathListForTagHash(ctx *Context, tagHash string) (newPath []string, err error) {
+	return pathList(ctx, tagHash, ``)
+}
+
+// PathListForGemset returns a PATH list appropriate for a given registered
+// ruby's tag hash and an activated gemset whose GEM_HOME is gemsetHome. The
+// gemset's bin dir precedes the ruby's default gem bin dir in the uru chunk.
+func PathListForGemset(ctx *Context, tagHash, gemsetHome string) (newPath []string, err error) {
+	return pathList(ctx, tagHash, gemsetHome)
+}
+
+func pathList(ctx *Context, tagHash, gemsetHome string) (newPath []string, err error) {
 	// If the current PATH has an uru chunk, remove it to create the base path.
 	// If not, the base path is the current PATH.
 	path := os.Getenv(`PATH`)
@@ -213,25 +225,28 @@ func PathListForTagHash(ctx *Context, tagHash string) (newPath []string, err err
 
 	// build new PATH based upon the ruby info identified by the tag hash
 	newRb := ctx.Registry.Rubies[tagHash]
-	if SysRbRegex.MatchString(newRb.TagLabel) {
+	if SysRbRegex.MatchString(newRb.TagLabel) && gemsetHome == `` {
 		// system ruby is already on base PATH so set new PATH to base PATH
 		newPath = base
 	} else {
 		// generate new uru chunk and prepend to base PATH
-		gemBinDir := filepath.Join(newRb.GemHome, `bin`)
-		uruChunk := []string{canary[0], gemBinDir, newRb.Home, canary[1]}
-
-		if runtime.GOOS == `windows` {
-			// Assume Windows users always install gems to the corresponding
-			// ruby installation. Do not prepend a generated GEM_HOME bindir
-			// to the uru chunk.
-			// TODO enhance to allow Windows users to customize GEM_HOME
-			uruChunk = []string{canary[0], newRb.Home, canary[1]}
+		uruChunk := []string{canary[0]}
+		if gemsetHome != `` {
+			uruChunk = append(uruChunk, filepath.Join(gemsetHome, `bin`))
+		}
+
+		// Assume Windows users always install gems to the corresponding
+		// ruby installation. Do not prepend a generated GEM_HOME bindir
+		// to the uru chunk.
+		// TODO enhance to allow Windows users to customize GEM_HOME
+		if runtime.GOOS != `windows` && newRb.GemHome != `` {
+			uruChunk = append(uruChunk, filepath.Join(newRb.GemHome, `bin`))
 		}
+		uruChunk = append(uruChunk, newRb.Home, canary[1])
 
 		newPath = append(uruChunk, base...)
 	}
-	log.Printf("[DEBUG] === %s path list ===\n  %#v\n", newRb.TagLabel, newPath)
+	log.Tracef("=== %s path list ===\n  %#v\n", newRb.TagLabel, newPath)
 
 	return
 }
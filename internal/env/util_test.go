@@ -4,8 +4,6 @@
 package env
 
 import (
-	"io/ioutil"
-	"log"
 	"os"
 	"reflect"
 	"sort"
@@ -45,11 +43,6 @@ var (
 	testTagHashes        = []string{`3577244517`, `444332046`, `3091568265`}
 )
 
-func init() {
-	// silence any logging done in the package files
-	log.SetOutput(ioutil.Discard)
-}
-
 func TestGetUruChunk(t *testing.T) {
 	prefix := strings.Join(
 		[]string{`/fake/tool/bin`, `/bogus/app/bin`},
@@ -0,0 +1,159 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+// Package log is uru's leveled logger. Log lines are written as a timestamp,
+// level and message followed by optional structured `key=value` fields, e.g.
+//
+//    2026-01-02T15:04:05.000Z DEBUG spawn exe=/opt/ruby/bin/ruby args="[-v]"
+//
+// Logging is disabled until a level is set, typically from the URU_LOG env
+// var or uru's `--log-level` option.
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line. A logger writes the lines whose level
+// is at or below its own level.
+type Level int
+
+const (
+	LevelOff Level = iota
+	LevelError
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+var levelNames = []string{`off`, `error`, `warn`, `info`, `debug`, `trace`}
+
+func (l Level) String() string {
+	if l < LevelOff || int(l) >= len(levelNames) {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named by a case insensitive level name.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	return LevelOff, fmt.Errorf("unknown log level `%s`; use one of %s", name, strings.Join(levelNames, `, `))
+}
+
+// Logger writes leveled, structured log lines to an io.Writer. It is safe for
+// concurrent use.
+type Logger struct {
+	mu    sync.Mutex
+	level Level
+	out   io.Writer
+	now   func() time.Time
+}
+
+// New returns a logger writing the lines at or below level to w.
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{level: level, out: w, now: time.Now}
+}
+
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = w
+}
+
+// Enabled indicates whether lines of the given level are written.
+func (l *Logger) Enabled(level Level) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return level != LevelOff && level <= l.level
+}
+
+// Log writes a message and its structured fields, given as alternating keys
+// and values, if the level is enabled.
+func (l *Logger) Log(level Level, msg string, kv ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var b bytes.Buffer
+	b.WriteString(strings.TrimRight(msg, "\n"))
+	for i := 0; i < len(kv); i += 2 {
+		var v interface{} = `MISSING`
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%s", kv[i], formatValue(v))
+	}
+	l.write(level, b.String())
+}
+
+// Logf writes a formatted message if the level is enabled.
+func (l *Logger) Logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.write(level, strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+}
+
+func (l *Logger) write(level Level, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.out, "%s %-5s %s\n", l.now().UTC().Format(`2006-01-02T15:04:05.000Z`),
+		strings.ToUpper(level.String()), line)
+}
+
+// formatValue formats a field value, quoting values that are empty or contain
+// white space, quotes or `=`.
+func formatValue(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case error:
+		s = t.Error()
+	case time.Duration:
+		s = t.Round(time.Millisecond).String()
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+
+	if s == `` || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// std is the process wide logger used by the package level functions.
+var std = New(ioutil.Discard, LevelOff)
+
+func SetLevel(level Level)     { std.SetLevel(level) }
+func SetOutput(w io.Writer)    { std.SetOutput(w) }
+func Enabled(level Level) bool { return std.Enabled(level) }
+
+func Error(msg string, kv ...interface{}) { std.Log(LevelError, msg, kv...) }
+func Warn(msg string, kv ...interface{})  { std.Log(LevelWarn, msg, kv...) }
+func Info(msg string, kv ...interface{})  { std.Log(LevelInfo, msg, kv...) }
+func Debug(msg string, kv ...interface{}) { std.Log(LevelDebug, msg, kv...) }
+func Trace(msg string, kv ...interface{}) { std.Log(LevelTrace, msg, kv...) }
+
+func Errorf(format string, args ...interface{}) { std.Logf(LevelError, format, args...) }
+func Warnf(format string, args ...interface{})  { std.Logf(LevelWarn, format, args...) }
+func Infof(format string, args ...interface{})  { std.Logf(LevelInfo, format, args...) }
+func Debugf(format string, args ...interface{}) { std.Logf(LevelDebug, format, args...) }
+func Tracef(format string, args ...interface{}) { std.Logf(LevelTrace, format, args...) }
@@ -0,0 +1,107 @@
+// Author: Jon Maken, All Rights Reserved
+// License: 3-clause BSD
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testNow = func() time.Time {
+	return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+}
+
+func newTestLogger(level Level) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := New(&buf, level)
+	l.now = testNow
+	return l, &buf
+}
+
+func TestParseLevel(t *testing.T) {
+	var tests = []struct {
+		name  string
+		level Level
+		ok    bool
+	}{
+		{`off`, LevelOff, true},
+		{`error`, LevelError, true},
+		{`WARN`, LevelWarn, true},
+		{`info`, LevelInfo, true},
+		{`Debug`, LevelDebug, true},
+		{`trace`, LevelTrace, true},
+		{`verbose`, LevelOff, false},
+		{``, LevelOff, false},
+	}
+
+	for _, v := range tests {
+		level, err := ParseLevel(v.name)
+		if (err == nil) != v.ok || level != v.level {
+			t.Errorf("ParseLevel(%q) not returning correct value\n  want: `%v, ok=%v`\n  got: `%v, %v`",
+				v.name, v.level, v.ok, level, err)
+		}
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	var tests = []struct {
+		level Level
+		want  string
+	}{
+		{LevelOff, ``},
+		{LevelError, "2026-01-02T15:04:05.000Z ERROR e\n"},
+		{LevelWarn, "2026-01-02T15:04:05.000Z ERROR e\n" +
+			"2026-01-02T15:04:05.000Z WARN  w\n"},
+		{LevelTrace, "2026-01-02T15:04:05.000Z ERROR e\n" +
+			"2026-01-02T15:04:05.000Z WARN  w\n" +
+			"2026-01-02T15:04:05.000Z INFO  i\n" +
+			"2026-01-02T15:04:05.000Z DEBUG d\n" +
+			"2026-01-02T15:04:05.000Z TRACE t\n"},
+	}
+
+	for _, v := range tests {
+		l, buf := newTestLogger(v.level)
+		l.Log(LevelError, `e`)
+		l.Log(LevelWarn, `w`)
+		l.Log(LevelInfo, `i`)
+		l.Logf(LevelDebug, "%s\n", `d`)
+		l.Logf(LevelTrace, `t`)
+
+		if got := buf.String(); got != v.want {
+			t.Errorf("Logger at level %v not writing correct lines\n  want: `%v`\n  got: `%v`",
+				v.level, v.want, got)
+		}
+	}
+}
+
+func TestLoggerFields(t *testing.T) {
+	var tests = []struct {
+		msg  string
+		kv   []interface{}
+		want string
+	}{
+		{`spawn`, []interface{}{`exe`, `/opt/ruby/bin/ruby`, `args`, []string{`-v`}},
+			`spawn exe=/opt/ruby/bin/ruby args=[-v]`},
+		{`exit`, []interface{}{`code`, 3, `duration`, 1234567 * time.Microsecond},
+			`exit code=3 duration=1.235s`},
+		{`failed`, []interface{}{`err`, errors.New(`file not found`)},
+			`failed err="file not found"`},
+		{`quoted`, []interface{}{`empty`, ``, `eq`, `a=b`, `q`, `say "hi"`},
+			`quoted empty="" eq="a=b" q="say \"hi\""`},
+		{`odd`, []interface{}{`key`},
+			`odd key=MISSING`},
+	}
+
+	for _, v := range tests {
+		l, buf := newTestLogger(LevelDebug)
+		l.Log(LevelDebug, v.msg, v.kv...)
+
+		want := "2026-01-02T15:04:05.000Z DEBUG " + v.want + "\n"
+		if got := buf.String(); got != want {
+			t.Errorf("Log() not writing correct fields\n  want: `%v`\n  got: `%v`", want, got)
+		}
+	}
+}